@@ -1,9 +1,9 @@
 package main
 
 import (
-	"archive/zip"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -14,13 +14,16 @@ import (
 func main() {
 	log.SetOutput(os.Stdout)
 	trigSource := flag.String("dir", "", "path to KML directory with Trig data")
+	upsert := flag.Bool("upsert", false, "update beacons imported before, keeping their OSM IDs, and report what changed")
+	cfg := trig.DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 	files, err := filepath.Glob(*trigSource + "/*.kmz")
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	db, err := trig.Connect()
+	db, err := trig.Open(cfg)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -31,23 +34,53 @@ func main() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	e := &extractor{db: db, upsert: *upsert}
 	for _, f := range files {
-		err = extractFiles(f, db)
+		err = e.extractFiles(f)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 	}
+	if *upsert {
+		e.report()
+	}
+}
+
+type extractor struct {
+	db      trig.Store
+	upsert  bool
+	summary trig.ImportSummary
 }
 
-func extractFiles(in string, db *trig.DB) error {
-	zr, err := zip.OpenReader(in)
+// extractFiles stores the beacons of each KML document in the KMZ archive in, named after the archive and document.
+func (e *extractor) extractFiles(in string) error {
+	r, err := trig.OpenKML(in)
 	if err != nil {
 		return err
 	}
-	defer zr.Close()
-	for _, f := range zr.File {
-		err := extractFile(f, db)
+	defer r.Close()
+	files := make([]string, 0, 1)
+	trigs := make(map[string][]*trig.Trig, 1)
+	for {
+		t, err := r.NextTrig()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		source := filepath.Base(in) + "/" + r.File()
+		if _, ok := trigs[source]; !ok {
+			files = append(files, source)
+		}
+		trigs[source] = append(trigs[source], t)
+	}
+	if len(r.Errors()) > 0 {
+		log.Printf("skipped %d placemarks in %s:\n%s", len(r.Errors()), in, r.Errors())
+	}
+	for _, source := range files {
+		err = e.extractFile(source, trigs[source])
 		if err != nil {
 			return err
 		}
@@ -55,15 +88,28 @@ func extractFiles(in string, db *trig.DB) error {
 	return nil
 }
 
-func extractFile(f *zip.File, db *trig.DB) error {
-	rc, err := f.Open()
-	if err != nil {
-		return err
+func (e *extractor) extractFile(source string, trigs []*trig.Trig) error {
+	if !e.upsert {
+		return e.db.Import(source, trigs)
 	}
-	defer rc.Close()
-	trigs, err := trig.Read(rc)
+	summary, err := e.db.Upsert(source, trigs)
 	if err != nil {
 		return err
 	}
-	return db.Import(trigs)
+	log.Printf("%s: %s", source, summary)
+	e.summary.Add(summary)
+	return nil
+}
+
+func (e *extractor) report() {
+	for _, t := range e.summary.New {
+		log.Printf("new %s %s", t.Number, t.Name)
+	}
+	for _, c := range e.summary.Changed {
+		log.Printf("changed %s", c)
+	}
+	for _, n := range e.summary.Removed {
+		log.Printf("removed %s", n)
+	}
+	log.Printf("total: %s", &e.summary)
 }
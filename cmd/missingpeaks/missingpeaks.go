@@ -8,7 +8,9 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/godfried/osmimport/osm"
 	"github.com/godfried/osmimport/osm/overpass"
 	"github.com/godfried/osmimport/poi"
 	"github.com/godfried/osmimport/sources/wcpeaks"
@@ -20,8 +22,23 @@ func main() {
 	flag.Float64Var(&bbox.Lat, "lat", -33.4, "latitude around which to focus")
 	flag.Float64Var(&bbox.Lon, "lon", 20.0, "longitude around which to focus")
 	flag.Float64Var(&bbox.RadiusKM, "radius", 500, "radius around centre to select points from, in km")
+	extract := flag.String("osm", "", "path to local OSM extract (.osm, .osm.gz or .osm.bz2) to query instead of Overpass")
+	cacheDir := flag.String("cache", "", "directory in which to cache Overpass results, disabled if empty")
+	cacheTTL := flag.Duration("cache-ttl", 24*time.Hour, "how long cached Overpass results stay valid")
+	refresh := flag.Bool("refresh", false, "ignore and replace cached Overpass results")
+	kmlOut := flag.String("kml", "", "path to a KML or KMZ file to write the OSM peaks to for review, disabled if empty")
 	flag.Parse()
-	err := run(*peaksSource, bbox)
+	if *cacheDir != "" {
+		overpass.UseCache(*cacheDir, *cacheTTL, *refresh)
+	}
+	if *extract != "" {
+		err := overpass.UseLocalFile(*extract)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	err := run(*peaksSource, *kmlOut, bbox)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -29,7 +46,7 @@ func main() {
 
 }
 
-func run(peaksSource string, bbox poi.CircleBox) error {
+func run(peaksSource, kmlOut string, bbox poi.CircleBox) error {
 	peaks, err := wcpeaks.Read(peaksSource)
 	if err != nil {
 		return err
@@ -39,6 +56,7 @@ func run(peaksSource string, bbox poi.CircleBox) error {
 	if err != nil {
 		return err
 	}
+	review := make([]osm.ReviewPOI, 0, len(results))
 	for _, r := range results {
 		found := false
 		if len(r.Names()) == 0 {
@@ -64,21 +82,29 @@ func run(peaksSource string, bbox poi.CircleBox) error {
 			}
 		}
 		if found {
+			review = append(review, osm.ReviewPOI{POI: r, Status: osm.ReviewMatched})
 			continue
 		}
 		name := r.Names()[0].Value
 		if len(partials) == 0 {
 			log.Printf("No match for peak: %s %s %f %f", name, r.TagMap["ele"], r.Lat, r.Lon)
 			log.Printf("https://htonl.dev.openstreetmap.org/ngi-tiles/#15/%f/%f", r.Lat, r.Lon)
+			review = append(review, osm.ReviewPOI{POI: r, Status: osm.ReviewNew, Note: "missing from " + peaksSource})
 		} else {
 			log.Printf("partial matches for %s %s %f %f", name, r.TagMap["ele"], r.Lat, r.Lon)
+			matches := make([]string, 0, len(partials))
 			for _, p := range partials {
 				log.Printf("%s %s %f", p.Name, p.Range, p.Ele)
+				matches = append(matches, fmt.Sprintf("%s (%s, %gm)", p.Name, p.Range, p.Ele))
 			}
 			log.Printf("https://htonl.dev.openstreetmap.org/ngi-tiles/#15/%f/%f", r.Lat, r.Lon)
+			review = append(review, osm.ReviewPOI{POI: r, Status: osm.ReviewFixme, Note: "partial matches: " + strings.Join(matches, ", ")})
 		}
 	}
-	return nil
+	if kmlOut == "" {
+		return nil
+	}
+	return osm.GenerateReviewKML(review, kmlOut)
 }
 
 func buildQuery(bb poi.CircleBox, queryTemplate string) string {
@@ -3,14 +3,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/godfried/osmimport/osm"
 	"github.com/godfried/osmimport/osm/overpass"
 	"github.com/godfried/osmimport/poi"
-
+	"github.com/godfried/osmimport/sources"
 	"github.com/godfried/osmimport/sources/sagns"
 )
 
@@ -21,9 +23,50 @@ func main() {
 	flag.Float64Var(&bbox.Lon, "lon", 20.0, "longitude around which to focus")
 	flag.Float64Var(&bbox.RadiusKM, "radius", 20, "radius around centre to select points from, in km")
 	out := flag.String("out", fmt.Sprintf("sagns-poi-%s.xml", time.Now().Format(time.RFC3339)), "path to output file")
-	limit := flag.Int("limit", 100, "number of points to process")
+	limit := flag.Int("limit", 100, "number of points to process, 0 for all")
+	extract := flag.String("osm", "", "path to local OSM extract (.osm, .osm.gz or .osm.bz2) to query instead of Overpass")
+	cacheDir := flag.String("cache", "", "directory in which to cache Overpass results, disabled if empty")
+	cacheTTL := flag.Duration("cache-ttl", 24*time.Hour, "how long cached Overpass results stay valid")
+	refresh := flag.Bool("refresh", false, "ignore and replace cached Overpass results")
+	kmlOut := flag.String("kml", "", "path to a KML or KMZ file to write the conflated POIs to for review, disabled if empty")
+	features := flag.String("sagns-features", "", "path to a JSON or CSV mapping of SAGNS features to OSM tags, defaults to the built-in mapping")
+	chunks := osm.ChunkOptions{}
+	flag.IntVar(&chunks.MaxElements, "chunk-size", 0, "split the output into files of at most this many points, disabled if 0")
+	flag.Float64Var(&chunks.TileDegrees, "tile", 0, "split the output into files per grid cell of this many degrees, disabled if 0")
+	export := flag.Bool("export", false, "write every mapped record within the radius to the output file as it is read, without looking for them in OSM, the whole file with -limit 0 -lat 0 -lon 0 -radius 0")
 	flag.Parse()
-	err := run(*sagnsSource, *out, bbox, *limit)
+	if *limit < 0 {
+		fmt.Println("-limit must be 0 or more")
+		os.Exit(1)
+	}
+	if *export && (*kmlOut != "" || chunks.Enabled()) {
+		fmt.Println("-export writes a single output file, it cannot be combined with -kml, -chunk-size or -tile")
+		os.Exit(1)
+	}
+	if *features != "" {
+		m, err := sagns.LoadMapping(*features)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		sagns.UseMapping(m)
+	}
+	if *cacheDir != "" {
+		overpass.UseCache(*cacheDir, *cacheTTL, *refresh)
+	}
+	if *extract != "" {
+		err := overpass.UseLocalFile(*extract)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	var err error
+	if *export {
+		err = exportAll(*sagnsSource, *out, bbox, *limit)
+	} else {
+		err = run(*sagnsSource, *out, *kmlOut, bbox, *limit, chunks)
+	}
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -31,32 +74,105 @@ func main() {
 
 }
 
-func run(sagnsSource, out string, bbox poi.CircleBox, limit int) error {
-	pois, err := sagns.Read(sagnsSource)
+// exportAll streams the records of sagnsSource in bbox to out, so the whole country can be exported without holding it
+// in memory.
+func exportAll(sagnsSource, out string, bbox poi.CircleBox, limit int) error {
+	r, err := sagns.Open(sagnsSource, bbox)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := osm.Create(out)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for ; limit <= 0 || n < limit; n++ {
+		p, err := r.NextPOI()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			w.Close()
+			return err
+		}
+		if err = w.WritePOI(p); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	logSkipped(r)
+	log.Printf("wrote %d POIs to %s", n, out)
+	return nil
+}
+
+// logSkipped reports the records r did not return.
+func logSkipped(r *sagns.Reader) {
+	if len(r.Unmapped) > 0 {
+		log.Printf("skipped records with unmapped features: %s", r.Unmapped)
+	}
+	if r.Uploaded > 0 {
+		log.Printf("skipped %d records already uploaded", r.Uploaded)
+	}
+	if len(r.Errors()) > 0 {
+		log.Printf("found %d malformed records:\n%s", len(r.Errors()), r.Errors())
+	}
+}
+
+func run(sagnsSource, out, kmlOut string, bbox poi.CircleBox, limit int, chunks osm.ChunkOptions) error {
+	r, err := sagns.Open(sagnsSource, bbox)
 	if err != nil {
 		return err
 	}
-	q := overpass.BuildQuery(overpass.SAGNSQuery, nil, bbox.RadiusKM*1000, bbox.Lat, bbox.Lon)
+	defer r.Close()
+	pois, err := sources.ReadAll(r, bbox)
+	if err != nil {
+		return err
+	}
+	logSkipped(r)
+	q := overpass.BuildQuery(overpass.DefaultQuery, sagns.QueryFilter(pois), bbox.RadiusKM*1000, bbox.Lat, bbox.Lon)
 	results, err := overpass.RunQuery(q)
 	if err != nil {
 		return err
 	}
-	resultMap := make(map[string]*overpass.Element, len(results))
+	elements := make([]poi.POI, 0, len(results))
 	for _, r := range results {
-		resultMap[r.TagMap["sagns_id"]] = r
+		elements = append(elements, r)
 	}
-	log.Printf("Loaded %d sagns datapoints", len(resultMap))
+	log.Printf("Loaded %d OSM elements", len(elements))
 	log.Printf("loaded %d POIs", len(pois))
+	report := poi.NewConflator("sagns_id").Conflate(pois, elements)
+	log.Printf("conflation results: %v", report.Summary())
 	boundedPOIs := make([]poi.POI, 0, limit)
-	for _, p := range pois {
-		if len(boundedPOIs) >= limit {
+	for _, m := range report {
+		if limit > 0 && len(boundedPOIs) >= limit {
 			break
 		}
-		_, ok := resultMap[p.Tags()["sagns_id"]]
-		if bbox.Contains(p) && !ok {
-			boundedPOIs = append(boundedPOIs, p)
+		switch m.Status {
+		case poi.StatusExact:
+			continue
+		case poi.StatusProbable, poi.StatusConflict:
+			log.Printf("%s match for %s: %s", m.Status, m.Source.Names(), strings.Join(m.Reasons, ", "))
+			m.Source.AddTag("fixme", fmt.Sprintf("check existing %s match", m.Status))
 		}
+		boundedPOIs = append(boundedPOIs, m.Source)
 	}
 	log.Printf("filtered to %d POIs", len(boundedPOIs))
-	return osm.GenerateXML(boundedPOIs, out)
+	if kmlOut != "" {
+		err = osm.GenerateReportKML(report, kmlOut)
+		if err != nil {
+			return err
+		}
+	}
+	written, err := osm.GenerateChunkedXML(boundedPOIs, chunks, out)
+	if err != nil {
+		return err
+	}
+	for _, c := range written {
+		log.Printf("wrote %d POIs to %s", len(c.POIs), c.File)
+	}
+	return nil
 }
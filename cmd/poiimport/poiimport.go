@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/godfried/osmimport/osm"
+	"github.com/godfried/osmimport/osm/api"
+	"github.com/godfried/osmimport/osm/overpass"
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+	"github.com/godfried/osmimport/sources/geojson"
+	_ "github.com/godfried/osmimport/sources/geonames"
+	"github.com/godfried/osmimport/sources/sagns"
+	"github.com/godfried/osmimport/sources/trig"
+)
+
+func main() {
+	bbox := poi.CircleBox{}
+	sourceName := flag.String("source", "", "data source to import, one of: "+strings.Join(sources.Names(), ", "))
+	in := flag.String("in", "", "path to source data")
+	flag.Float64Var(&bbox.Lat, "lat", -33.4, "latitude around which to focus")
+	flag.Float64Var(&bbox.Lon, "lon", 20.0, "longitude around which to focus")
+	flag.Float64Var(&bbox.RadiusKM, "radius", 20, "radius around centre to select points from, in km")
+	out := flag.String("out", "", "path to output file, GeoJSON if it ends in .geojson, defaults to <source>-poi-<time>.xml or .osc when merging")
+	merge := flag.Bool("merge", false, "merge source tags into matching OSM elements and write an osmChange file")
+	limit := flag.Int("limit", 100, "number of points to process, 0 for all")
+	extract := flag.String("osm", "", "path to local OSM extract (.osm, .osm.gz or .osm.bz2) to query instead of Overpass")
+	cacheDir := flag.String("cache", "", "directory in which to cache Overpass results, disabled if empty")
+	cacheTTL := flag.Duration("cache-ttl", 24*time.Hour, "how long cached Overpass results stay valid")
+	refresh := flag.Bool("refresh", false, "ignore and replace cached Overpass results")
+	features := flag.String("sagns-features", "", "path to a JSON or CSV mapping of SAGNS features to OSM tags, defaults to the built-in mapping")
+	geojsonKey := flag.String("geojson-key", "ref", "tag identifying GeoJSON features already imported into OSM")
+	geojsonFilter := flag.String("geojson-filter", "", "comma separated key or key=value tags selecting the OSM elements to dedupe GeoJSON features against, defaults to those with the -geojson-key tag")
+	upload := flag.Bool("upload", false, "upload the POIs without existing matches and the merged elements in a new changeset, authorised by $"+api.TokenEnv)
+	apiURL := flag.String("api", api.DefaultURL, "URL of the OSM API to upload to")
+	dryRun := flag.Bool("dry-run", false, "log the upload requests instead of sending them, implies -upload")
+	comment := flag.String("comment", "", "changeset comment, required to upload")
+	changesetSource := flag.String("changeset-source", "", "changeset source tag")
+	trigConfig := trig.DefaultConfig()
+	trigConfig.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if *limit < 0 {
+		fmt.Println("-limit must be 0 or more")
+		os.Exit(1)
+	}
+	trig.UseConfig(trigConfig)
+	trig.UseBox(bbox)
+	var client *api.Client
+	if *upload || *dryRun {
+		if *comment == "" {
+			fmt.Println("uploading needs a changeset -comment")
+			os.Exit(1)
+		}
+		token := os.Getenv(api.TokenEnv)
+		if token == "" && !*dryRun {
+			fmt.Printf("uploading needs an OAuth 2.0 token in $%s\n", api.TokenEnv)
+			os.Exit(1)
+		}
+		client = api.NewClient(*apiURL, token)
+		client.DryRun = *dryRun
+	}
+	filter, err := parseFilter(*geojsonFilter)
+	if err != nil {
+		fmt.Printf("invalid -geojson-filter: %s\n", err)
+		os.Exit(1)
+	}
+	geojson.UseConflation(*geojsonKey, filter)
+	if *features != "" {
+		m, err := sagns.LoadMapping(*features)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		sagns.UseMapping(m)
+	}
+	if *cacheDir != "" {
+		overpass.UseCache(*cacheDir, *cacheTTL, *refresh)
+	}
+	if *extract != "" {
+		err := overpass.UseLocalFile(*extract)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *out == "" {
+		ext := "xml"
+		if *merge {
+			ext = "osc"
+		}
+		*out = fmt.Sprintf("%s-poi-%s.%s", *sourceName, time.Now().Format(time.RFC3339), ext)
+	}
+	err = run(*sourceName, *in, *out, bbox, *limit, *merge, client, api.ImportTags(*comment, *changesetSource))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(sourceName, in, out string, bbox poi.CircleBox, limit int, merge bool, client *api.Client, tags map[string]string) error {
+	src, err := sources.Lookup(sourceName)
+	if err != nil {
+		return err
+	}
+	r, err := src.Open(in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	pois, err := sources.ReadAll(r, bbox)
+	if err != nil {
+		return err
+	}
+	log.Printf("loaded %d %s POIs", len(pois), sourceName)
+	if er, ok := r.(sources.ErrorReporter); ok && len(er.Errors()) > 0 {
+		log.Printf("found %d malformed records:\n%s", len(er.Errors()), er.Errors())
+	}
+	filter := src.Filter()
+	if qf, ok := src.(sources.QueryFilterer); ok {
+		filter = qf.QueryFilter(pois)
+	}
+	q := overpass.BuildQuery(overpass.DefaultQuery, filter, bbox.Radius(), bbox.Lat, bbox.Lon)
+	results, err := overpass.RunQuery(q)
+	if err != nil {
+		return err
+	}
+	elements := make([]poi.POI, 0, len(results))
+	for _, r := range results {
+		elements = append(elements, r)
+	}
+	report := poi.NewConflator(src.DedupeKey()).Conflate(pois, elements)
+	log.Printf("conflated against %d OSM elements: %v", len(elements), report.Summary())
+	selected := make([]poi.POI, 0, limit)
+	// fresh holds the selected POIs with no existing match, the only ones safe to create without review.
+	fresh := make([]poi.POI, 0, limit)
+	modified := make([]*overpass.Element, 0, len(report))
+	for _, m := range report {
+		if m.Status == poi.StatusExact {
+			if merge {
+				if e := mergeMatch(m); e != nil {
+					modified = append(modified, e)
+				}
+			}
+			continue
+		}
+		// The limit only applies to the POIs to be created, exact matches are still merged once it is reached.
+		if limit > 0 && len(selected) >= limit {
+			continue
+		}
+		switch m.Status {
+		case poi.StatusProbable, poi.StatusConflict:
+			log.Printf("%s match for %s: %s", m.Status, m.Source.Names(), strings.Join(m.Reasons, ", "))
+			m.Source.AddTag("fixme", fmt.Sprintf("check existing %s match", m.Status))
+		case poi.StatusNew:
+			fresh = append(fresh, m.Source)
+		}
+		selected = append(selected, m.Source)
+	}
+	log.Printf("filtered to %d POIs", len(selected))
+	if merge {
+		log.Printf("merged tags into %d OSM elements", len(modified))
+		err = osm.GenerateChangeXML(selected, modified, nil, out)
+	} else if strings.HasSuffix(out, ".geojson") {
+		err = geojson.WriteFile(selected, out)
+	} else {
+		err = osm.GenerateXML(selected, out)
+	}
+	if err != nil || client == nil {
+		return err
+	}
+	if held := len(selected) - len(fresh); held > 0 {
+		log.Printf("not uploading %d POIs with possible existing matches, review them in %s", held, out)
+	}
+	return uploadChange(client, tags, src, in, fresh, modified)
+}
+
+// uploadChange creates selected and modifies modified in a new changeset with tags, recording the IDs assigned to the
+// new nodes in src if it supports it.
+func uploadChange(client *api.Client, tags map[string]string, src sources.Source, in string, selected []poi.POI, modified []*overpass.Element) error {
+	result, err := client.UploadChange(context.Background(), tags, osm.NewOSMChange(selected, modified, nil))
+	if err != nil {
+		return err
+	}
+	if result.DryRun {
+		log.Printf("dry run: would have uploaded %d new and %d modified elements", len(selected), len(modified))
+		return nil
+	}
+	// NewOSMChange numbers the node created for the ith POI in SortNew order -(i+1).
+	created := result.Created("node")
+	uploaded := make([]sources.Uploaded, 0, len(created))
+	for i, p := range osm.SortNew(selected) {
+		if id, ok := created[-int64(i+1)]; ok {
+			uploaded = append(uploaded, sources.Uploaded{POI: p, OSMID: id})
+		}
+	}
+	log.Printf("uploaded changeset %d: created %d nodes, modified %d elements", result.Changeset, len(uploaded), len(modified))
+	for _, u := range uploaded {
+		log.Printf("created node %d for %s", u.OSMID, u.POI)
+	}
+	rec, ok := src.(sources.IDRecorder)
+	if !ok {
+		log.Printf("%s source cannot record OSM IDs, its POIs will be proposed again", src.Name())
+		return nil
+	}
+	failed, err := rec.RecordOSMIDs(in, uploaded)
+	if err != nil {
+		return fmt.Errorf("uploaded changeset %d but could not record its node IDs: %w", result.Changeset, err)
+	}
+	for _, err := range failed {
+		log.Printf("uploaded changeset %d but %s", result.Changeset, err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not record %d of the %d node IDs in changeset %d", len(failed), len(uploaded), result.Changeset)
+	}
+	return nil
+}
+
+// parseFilter reads comma separated key or key=value tags.
+func parseFilter(s string) ([]poi.Attribute, error) {
+	if s == "" {
+		return nil, nil
+	}
+	attrs := make([]poi.Attribute, 0, 2)
+	for _, kv := range strings.Split(s, ",") {
+		a, err := poi.ParseAttribute(kv)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs, nil
+}
+
+func mergeMatch(m *poi.Match) *overpass.Element {
+	e, ok := m.Target.(*overpass.Element)
+	if !ok {
+		return nil
+	}
+	merged, diffs := e.Merge(m.Source, poi.DefaultMergePolicy)
+	for _, d := range diffs {
+		if d.Kind == poi.TagConflicting {
+			log.Printf("%s %d: not overwriting %s", e.Type, e.ID, d)
+		}
+	}
+	if !poi.Modified(diffs) {
+		return nil
+	}
+	log.Printf("%s %d: %v", e.Type, e.ID, diffs)
+	return merged
+}
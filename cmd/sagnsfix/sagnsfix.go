@@ -7,7 +7,6 @@ import (
 
 	"github.com/godfried/osmimport/osm"
 	"github.com/godfried/osmimport/osm/overpass"
-	"github.com/godfried/osmimport/poi"
 )
 
 func main() {
@@ -21,20 +20,25 @@ func main() {
 );
 out meta;
 `
-	out := flag.String("out", "sagns-id-fix.xml", "path to output file")
+	out := flag.String("out", "sagns-id-fix.osc", "path to output file")
+	extract := flag.String("osm", "", "path to local OSM extract (.osm, .osm.gz or .osm.bz2) to query instead of Overpass")
 	flag.Parse()
+	if *extract != "" {
+		err := overpass.UseLocalFile(*extract)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	es, err := overpass.RunQuery(query)
 	if err != nil {
 		log.Fatal(err)
 	}
-	pois := make([]poi.POI, 0, len(es))
 	for _, e := range es {
 		v := e.TagMap["sagnsid"]
 		delete(e.TagMap, "sagnsid")
 		e.TagMap["sagns_id"] = v
-		pois = append(pois, e)
 	}
-	err = osm.GenerateUpdateXML(pois, *out)
+	err = osm.GenerateChangeXML(nil, es, nil, *out)
 	if err != nil {
 		log.Fatal(err)
 	}
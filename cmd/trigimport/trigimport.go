@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,29 +18,55 @@ import (
 func main() {
 	log.SetOutput(os.Stdout)
 	bbox := poi.CircleBox{}
-	limit := flag.Int("limit", 10, "number of points to export")
+	limit := flag.Int("limit", 10, "number of points to export, 0 for all")
 	out := flag.String("out", fmt.Sprintf("trig-poi-%s.xml", time.Now().Format(time.RFC3339)), "path to output file")
 	flag.Float64Var(&bbox.Lat, "lat", -33.4, "latitude around which to focus")
 	flag.Float64Var(&bbox.Lon, "lon", 20.0, "longitude around which to focus")
 	flag.Float64Var(&bbox.RadiusKM, "radius", 20, "radius around centre to select points from, in km")
+	extract := flag.String("osm", "", "path to local OSM extract (.osm, .osm.gz or .osm.bz2) to query instead of Overpass")
+	cacheDir := flag.String("cache", "", "directory in which to cache Overpass results, disabled if empty")
+	cacheTTL := flag.Duration("cache-ttl", 24*time.Hour, "how long cached Overpass results stay valid")
+	refresh := flag.Bool("refresh", false, "ignore and replace cached Overpass results")
+	kmlOut := flag.String("kml", "", "path to a KML or KMZ file to write the conflated beacons to for review, disabled if empty")
+	workers := flag.Int("workers", 4, "number of concurrent database updates")
+	batchSize := flag.Int("batch", 100, "number of OSM IDs to update per transaction")
+	cfg := trig.DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	chunks := osm.ChunkOptions{}
+	flag.IntVar(&chunks.MaxElements, "chunk-size", 0, "split the output into files of at most this many points, disabled if 0")
+	flag.Float64Var(&chunks.TileDegrees, "tile", 0, "split the output into files per grid cell of this many degrees, disabled if 0")
 	flag.Parse()
-	err := run(bbox, *limit, *out)
+	if *cacheDir != "" {
+		overpass.UseCache(*cacheDir, *cacheTTL, *refresh)
+	}
+	if *extract != "" {
+		err := overpass.UseLocalFile(*extract)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	err := run(cfg, bbox, *limit, chunks, *out, *kmlOut, *workers, *batchSize)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
-func run(bbox poi.CircleBox, limit int, out string) error {
-	db, err := trig.Connect()
+func run(cfg trig.Config, bbox poi.CircleBox, limit int, chunks osm.ChunkOptions, out, kmlOut string, workers, batchSize int) error {
+	db, err := trig.Open(cfg)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
-	pois, err := db.QueryIncomplete(100000)
+	trigs, err := db.QueryIncompleteWithin(bbox)
 	if err != nil {
 		return err
 	}
+	pois := make([]poi.POI, 0, len(trigs))
+	for _, t := range trigs {
+		pois = append(pois, t)
+	}
 	log.Printf("loaded %d POIs", len(pois))
 	boundedPOIs := make([]poi.POI, 0, len(pois))
 	q := overpass.BuildQuery(overpass.DefaultQuery, []poi.Attribute{{Key: "man_made", Value: "survey_point"}}, bbox.RadiusKM*1000, bbox.Lat, bbox.Lon)
@@ -48,62 +74,118 @@ func run(bbox poi.CircleBox, limit int, out string) error {
 	if err != nil {
 		return err
 	}
-	resultMap := make(map[string]*overpass.Element, len(results))
+	elements := make([]poi.POI, 0, len(results))
 	for _, r := range results {
-		ref, ok := r.TagMap["ref"]
-		if !ok {
-			continue
-		}
-		if exists, ok := resultMap[ref]; ok {
-			var ename, rname string
-			for _, n := range exists.Names() {
-				ename = n.Value
-				break
-			}
-			for _, n := range r.Names() {
-				rname = n.Value
-				break
-			}
-			log.Printf("ref %s already exists: %s %f %f and %s %f %f", ref, rname, r.Lat, r.Lon, ename, exists.Lat, exists.Lon)
-			continue
-		}
-		resultMap[ref] = r
+		elements = append(elements, r)
 	}
-	wg := &sync.WaitGroup{}
-	defer wg.Wait()
-	for _, p := range pois {
-		if len(boundedPOIs) >= limit {
+	conflator := poi.NewConflator("ref")
+	conflator.AltKeys = trig.AltRefs
+	conflator.RequireKey = true
+	report := conflator.Conflate(pois, elements)
+	log.Printf("conflated against %d survey points: %v", len(elements), report.Summary())
+	matched := make([]*trig.Trig, 0, len(report))
+	for _, m := range report {
+		if limit > 0 && len(boundedPOIs) >= limit {
 			break
 		}
-		if !bbox.Contains(p) {
+		p := m.Source.(*trig.Trig)
+		// Only a matching ref is trusted enough to be stored, later runs skip beacons with an OSM ID.
+		if m.Status == poi.StatusExact && m.ByKey {
+			p.OSMID = m.Target.(*overpass.Element).ID
+			matched = append(matched, p)
 			continue
 		}
-
-		match, ok := resultMap[p.Number.String()]
-		if ok && poi.Distance(match.Latitude(), match.Longitude(), p.Latitude(), p.Longitude()) < 1000 {
-			wg.Add(1)
-			go update(p, match.ID, db, wg)
-			continue
-		}
-		potentialMatch, ok := resultMap[strconv.Itoa(p.Number.Number)]
-		if ok && poi.Distance(potentialMatch.Latitude(), potentialMatch.Longitude(), p.Latitude(), p.Longitude()) < 1000 {
-			log.Printf("adding fixme to %s", p.Name)
+		if m.Status != poi.StatusNew {
+			log.Printf("adding fixme to %s: %s match: %s", p.Name, m.Status, strings.Join(m.Reasons, ", "))
 			p.AddTag("fixme", "check existing survey_point")
 		}
 		boundedPOIs = append(boundedPOIs, p)
 		log.Printf("selected trig beacon %s:%s (total %d)", p.Name, p.Number, len(boundedPOIs))
 	}
 	log.Printf("filtered to %d POIs", len(boundedPOIs))
-	return osm.GenerateXML(boundedPOIs, out)
+	summary := updateOSMIDs(db, matched, workers, batchSize)
+	log.Printf("updated OSM IDs of %d beacons, %d failed", summary.updated, len(summary.failed))
+	for _, err := range summary.failed {
+		log.Print(err)
+	}
+	if kmlOut != "" {
+		err = osm.GenerateReportKML(report, kmlOut)
+		if err != nil {
+			return err
+		}
+	}
+	written, err := osm.GenerateChunkedXML(boundedPOIs, chunks, out)
+	if err != nil {
+		return err
+	}
+	for _, c := range written {
+		log.Printf("wrote %d POIs to %s", len(c.POIs), c.File)
+	}
+	return nil
 }
 
-func update(t *trig.Trig, id uint64, db *trig.DB, wg *sync.WaitGroup) {
-	defer wg.Done()
-	t.OSMID = id
-	err := db.UpdateOSMID(t)
-	if err != nil {
-		log.Print(err)
-	} else {
+type updateSummary struct {
+	updated int
+	failed  []error
+}
+
+// updateOSMIDs stores the OSM IDs of trigs in batches, using at most workers database connections at once. A batch
+// which fails is retried one beacon at a time so that only the failing beacons are left without an OSM ID.
+func updateOSMIDs(db trig.Store, trigs []*trig.Trig, workers, batchSize int) updateSummary {
+	if workers < 1 {
+		workers = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	batches := make(chan []*trig.Trig)
+	results := make(chan updateSummary)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				results <- updateBatch(db, batch)
+			}
+		}()
+	}
+	go func() {
+		for start := 0; start < len(trigs); start += batchSize {
+			end := start + batchSize
+			if end > len(trigs) {
+				end = len(trigs)
+			}
+			batches <- trigs[start:end]
+		}
+		close(batches)
+		wg.Wait()
+		close(results)
+	}()
+	summary := updateSummary{}
+	for r := range results {
+		summary.updated += r.updated
+		summary.failed = append(summary.failed, r.failed...)
+	}
+	return summary
+}
+
+func updateBatch(db trig.Store, batch []*trig.Trig) updateSummary {
+	if err := db.UpdateOSMIDs(batch); err == nil {
+		for _, t := range batch {
+			log.Printf("Added OSMID %d for %s:%s", t.OSMID, t.Name, t.Number)
+		}
+		return updateSummary{updated: len(batch)}
+	}
+	summary := updateSummary{}
+	for _, t := range batch {
+		err := db.UpdateOSMID(t)
+		if err != nil {
+			summary.failed = append(summary.failed, err)
+			continue
+		}
 		log.Printf("Added OSMID %d for %s:%s", t.OSMID, t.Name, t.Number)
+		summary.updated++
 	}
+	return summary
 }
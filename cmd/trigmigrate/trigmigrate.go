@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/godfried/osmimport/sources/trig"
+)
+
+func main() {
+	log.SetOutput(os.Stdout)
+	status := flag.Bool("status", false, "only print the schema version instead of migrating")
+	cfg := trig.DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	err := run(cfg, *status)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg trig.Config, status bool) error {
+	db, err := trig.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	log.Printf("schema version %d, latest is %d", version, db.LatestSchemaVersion())
+	if status {
+		return nil
+	}
+	applied, err := db.Migrate()
+	if err != nil {
+		return err
+	}
+	log.Printf("applied %d migrations", applied)
+	return nil
+}
@@ -0,0 +1,216 @@
+// Package api uploads changes through the OpenStreetMap API 0.6, in a changeset opened for the purpose.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godfried/osmimport/osm"
+)
+
+const (
+	// DefaultURL is the API of the main OSM database.
+	DefaultURL = "https://api.openstreetmap.org/api/0.6"
+	// DevURL is the API of the development server, for trying out imports.
+	DevURL = "https://master.apis.dev.openstreetmap.org/api/0.6"
+	// TokenEnv is the environment variable commands read an OAuth 2.0 access token from.
+	TokenEnv = "OSM_API_TOKEN"
+	// MaxChangesetElements is the most elements the API accepts in a single changeset.
+	MaxChangesetElements = 10000
+)
+
+// Client uploads changes to an OSM API.
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+	// Token is an OAuth 2.0 access token with the write_api scope.
+	Token     string
+	UserAgent string
+	// DryRun logs the requests which would change the database instead of sending them.
+	DryRun bool
+}
+
+// NewClient returns a client for the API at url, authorised by token.
+func NewClient(url, token string) *Client {
+	return &Client{
+		URL:        strings.TrimSuffix(url, "/"),
+		HTTPClient: &http.Client{Timeout: 5 * time.Minute},
+		Token:      token,
+		UserAgent:  "osmimport",
+	}
+}
+
+// Error is returned when the API responds with a non-200 status.
+type Error struct {
+	Method, Path string
+	StatusCode   int
+	Status       string
+	Message      string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s: %s", e.Method, e.Path, e.Status)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", e.Method, e.Path, e.Status, e.Message)
+}
+
+// ImportTags returns the changeset tags the import guidelines ask for, with import=yes.
+func ImportTags(comment, source string) map[string]string {
+	tags := map[string]string{
+		"comment":    comment,
+		"import":     "yes",
+		"created_by": "osmimport",
+	}
+	if source != "" {
+		tags["source"] = source
+	}
+	return tags
+}
+
+type changesetTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+type changesetDoc struct {
+	XMLName xml.Name       `xml:"osm"`
+	Tags    []changesetTag `xml:"changeset>tag"`
+}
+
+// OpenChangeset creates a changeset with tags, returning its ID, 0 in a dry run.
+func (c *Client) OpenChangeset(ctx context.Context, tags map[string]string) (uint64, error) {
+	if tags["comment"] == "" {
+		return 0, fmt.Errorf("changeset needs a comment")
+	}
+	doc := changesetDoc{Tags: make([]changesetTag, 0, len(tags))}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc.Tags = append(doc.Tags, changesetTag{Key: k, Value: tags[k]})
+	}
+	body, err := xml.Marshal(doc)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.write(ctx, http.MethodPut, "/changeset/create", body)
+	if err != nil || resp == nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(string(resp)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected changeset ID %q: %s", resp, err)
+	}
+	return id, nil
+}
+
+// Upload applies change in the changeset id, returning the IDs and versions the API assigned.
+func (c *Client) Upload(ctx context.Context, id uint64, change *osm.OSMChange) (*DiffResult, error) {
+	if n := change.Len(); n > MaxChangesetElements {
+		return nil, fmt.Errorf("change has %d elements, more than the %d allowed in a changeset", n, MaxChangesetElements)
+	}
+	change.SetChangeset(id)
+	body, err := xml.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.write(ctx, http.MethodPost, fmt.Sprintf("/changeset/%d/upload", id), body)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return &DiffResult{DryRun: true}, nil
+	}
+	return ParseDiffResult(resp)
+}
+
+// CloseChangeset closes the changeset id.
+func (c *Client) CloseChangeset(ctx context.Context, id uint64) error {
+	_, err := c.write(ctx, http.MethodPut, fmt.Sprintf("/changeset/%d/close", id), nil)
+	return err
+}
+
+// UploadChange uploads change in a new changeset with tags, closing the changeset whether or not the upload succeeds.
+func (c *Client) UploadChange(ctx context.Context, tags map[string]string, change *osm.OSMChange) (*DiffResult, error) {
+	id, err := c.OpenChangeset(ctx, tags)
+	if err != nil {
+		return nil, fmt.Errorf("could not open changeset: %w", err)
+	}
+	result, err := c.Upload(ctx, id, change)
+	cerr := c.CloseChangeset(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not upload to changeset %d: %w", id, err)
+	}
+	if cerr != nil {
+		return nil, fmt.Errorf("could not close changeset %d: %w", id, cerr)
+	}
+	result.Changeset = id
+	return result, nil
+}
+
+// write sends a request which changes the database, or only logs it in a dry run, returning a nil body.
+func (c *Client) write(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	if c.DryRun {
+		log.Printf("dry run: %s %s%s\n%s", method, c.URL, path, body)
+		return nil, nil
+	}
+	return c.do(ctx, method, path, body)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	var in io.Reader
+	if body != nil {
+		in = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, in)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := resp.Header.Get("Error")
+		if msg == "" {
+			msg = strings.TrimSpace(string(data))
+		}
+		return nil, &Error{Method: method, Path: path, StatusCode: resp.StatusCode, Status: resp.Status, Message: msg}
+	}
+	// A successful write must not be mistaken for a dry run.
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
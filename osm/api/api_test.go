@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godfried/osmimport/osm"
+	"github.com/godfried/osmimport/osm/overpass"
+	"github.com/godfried/osmimport/poi"
+)
+
+type testPOI struct {
+	lat, lon float64
+	tags     map[string]string
+}
+
+func (p testPOI) String() string     { return p.tags["name"] }
+func (p testPOI) Latitude() float64  { return p.lat }
+func (p testPOI) Longitude() float64 { return p.lon }
+func (p testPOI) Names() []poi.Name {
+	return []poi.Name{{Key: poi.NameKeyDefault, Value: p.tags["name"]}}
+}
+func (p testPOI) Tags() map[string]string  { return p.tags }
+func (p testPOI) AddTag(key, value string) { p.tags[key] = value }
+
+func testChange() *osm.OSMChange {
+	create := []poi.POI{
+		testPOI{lat: -33.9626, lon: 18.4039, tags: map[string]string{"name": "Table Mountain", "natural": "peak"}},
+		testPOI{lat: -33.9355, lon: 18.3892, tags: map[string]string{"name": "Lion's Head", "natural": "peak"}},
+	}
+	modify := []*overpass.Element{{Type: "node", ID: 77, Version: 3, Lat: -33.9, Lon: 18.4, TagMap: map[string]string{"natural": "peak"}}}
+	return osm.NewOSMChange(create, modify, nil)
+}
+
+// fakeAPI stands in for the OSM API, recording the requests it receives.
+type fakeAPI struct {
+	t        *testing.T
+	mu       sync.Mutex
+	requests []string
+	tags     map[string]string
+	upload   func(w http.ResponseWriter, body []byte)
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	switch r.Method + " " + r.URL.Path {
+	case "PUT /api/0.6/changeset/create":
+		var doc changesetDoc
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			f.t.Errorf("bad changeset: %s", err)
+		}
+		f.tags = make(map[string]string)
+		for _, t := range doc.Tags {
+			f.tags[t.Key] = t.Value
+		}
+		fmt.Fprint(w, "42")
+	case "POST /api/0.6/changeset/42/upload":
+		f.upload(w, body)
+	case "PUT /api/0.6/changeset/42/close":
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeAPI(t *testing.T, upload func(w http.ResponseWriter, body []byte)) (*fakeAPI, *Client) {
+	f := &fakeAPI{t: t, upload: upload}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewClient(srv.URL+"/api/0.6/", "secret")
+}
+
+func TestUploadChange(t *testing.T) {
+	f, c := newFakeAPI(t, func(w http.ResponseWriter, body []byte) {
+		for _, want := range []string{`<node lat="-33.9626" lon="18.4039" id="-1" visible="true" changeset="42">`, `<node id="77" version="3" changeset="42"`} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("upload missing %s:\n%s", want, body)
+			}
+		}
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<diffResult version="0.6" generator="fake">
+  <node old_id="-1" new_id="1001" new_version="1"/>
+  <node old_id="-2" new_id="1002" new_version="1"/>
+  <node old_id="77" new_id="77" new_version="4"/>
+</diffResult>`)
+	})
+	result, err := c.UploadChange(context.Background(), ImportTags("Import trig beacons", "NGI"), testChange())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"PUT /api/0.6/changeset/create", "POST /api/0.6/changeset/42/upload", "PUT /api/0.6/changeset/42/close"}; !reflect.DeepEqual(f.requests, want) {
+		t.Errorf("requests = %v, want %v", f.requests, want)
+	}
+	if want := map[string]string{"comment": "Import trig beacons", "source": "NGI", "import": "yes", "created_by": "osmimport"}; !reflect.DeepEqual(f.tags, want) {
+		t.Errorf("changeset tags = %v, want %v", f.tags, want)
+	}
+	if result.Changeset != 42 || result.DryRun {
+		t.Errorf("result = %+v, want changeset 42", result)
+	}
+	if want := map[int64]uint64{-1: 1001, -2: 1002}; !reflect.DeepEqual(result.Created("node"), want) {
+		t.Errorf("Created() = %v, want %v", result.Created("node"), want)
+	}
+	if len(result.Created("way")) != 0 {
+		t.Errorf("Created(way) = %v, want none", result.Created("way"))
+	}
+}
+
+func TestUploadChangeConflict(t *testing.T) {
+	f, c := newFakeAPI(t, func(w http.ResponseWriter, body []byte) {
+		w.Header().Set("Error", "Version mismatch: Provided 3, server had: 4 of Node 77")
+		w.WriteHeader(http.StatusConflict)
+	})
+	_, err := c.UploadChange(context.Background(), ImportTags("Import", ""), testChange())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || !strings.Contains(apiErr.Message, "Version mismatch") {
+		t.Fatalf("UploadChange() error = %v, want version conflict", err)
+	}
+	if last := f.requests[len(f.requests)-1]; last != "PUT /api/0.6/changeset/42/close" {
+		t.Errorf("changeset left open, last request %s", last)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	f, c := newFakeAPI(t, nil)
+	c.DryRun = true
+	result, err := c.UploadChange(context.Background(), ImportTags("Import", ""), testChange())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.DryRun || len(result.Elements) != 0 {
+		t.Errorf("result = %+v, want an empty dry run", result)
+	}
+	if len(f.requests) != 0 {
+		t.Errorf("dry run sent %v", f.requests)
+	}
+}
+
+func TestOpenChangesetErrors(t *testing.T) {
+	_, c := newFakeAPI(t, nil)
+	if _, err := c.OpenChangeset(context.Background(), map[string]string{"import": "yes"}); err == nil {
+		t.Error("OpenChangeset() without a comment should fail")
+	}
+	c.Token = "wrong"
+	_, err := c.OpenChangeset(context.Background(), ImportTags("Import", ""))
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("OpenChangeset() error = %v, want unauthorized", err)
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	f, c := newFakeAPI(t, nil)
+	create := make([]poi.POI, MaxChangesetElements+1)
+	for i := range create {
+		create[i] = testPOI{tags: map[string]string{}}
+	}
+	if _, err := c.Upload(context.Background(), 42, osm.NewOSMChange(create, nil, nil)); err == nil {
+		t.Error("Upload() of too many elements should fail")
+	}
+	if len(f.requests) != 0 {
+		t.Errorf("sent %v", f.requests)
+	}
+}
+
+func TestParseDiffResult(t *testing.T) {
+	d, err := ParseDiffResult([]byte(`<diffResult><way old_id="-3" new_id="9" new_version="1"/><node old_id="5"/></diffResult>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiffElement{
+		{XMLName: xml.Name{Local: "way"}, OldID: -3, NewID: 9, NewVersion: 1},
+		{XMLName: xml.Name{Local: "node"}, OldID: 5},
+	}
+	if !reflect.DeepEqual(d.Elements, want) {
+		t.Errorf("Elements = %+v, want %+v", d.Elements, want)
+	}
+	if _, err := ParseDiffResult([]byte("<html>")); err == nil {
+		t.Error("ParseDiffResult() of HTML should fail")
+	}
+}
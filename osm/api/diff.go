@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// DiffResult is the API's response to an upload, listing the new ID and version of each element changed.
+type DiffResult struct {
+	XMLName  xml.Name      `xml:"diffResult"`
+	Elements []DiffElement `xml:",any"`
+	// Changeset is the changeset the diff was uploaded in.
+	Changeset uint64 `xml:"-"`
+	// DryRun is set if nothing was uploaded.
+	DryRun bool `xml:"-"`
+}
+
+// DiffElement is the outcome of uploading an element. NewID and NewVersion are zero for deleted elements.
+type DiffElement struct {
+	XMLName    xml.Name
+	OldID      int64  `xml:"old_id,attr"`
+	NewID      uint64 `xml:"new_id,attr"`
+	NewVersion uint32 `xml:"new_version,attr"`
+}
+
+// Type is the element's type: node, way or relation.
+func (e DiffElement) Type() string {
+	return e.XMLName.Local
+}
+
+func ParseDiffResult(data []byte) (*DiffResult, error) {
+	d := &DiffResult{}
+	err := xml.Unmarshal(data, d)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse diffResult: %s", err)
+	}
+	return d, nil
+}
+
+// Created maps the placeholder negative IDs of the elements of type typ created by the upload to their new IDs.
+func (d *DiffResult) Created(typ string) map[int64]uint64 {
+	ids := make(map[int64]uint64)
+	for _, e := range d.Elements {
+		if e.Type() == typ && e.OldID < 0 && e.NewID != 0 {
+			ids[e.OldID] = e.NewID
+		}
+	}
+	return ids
+}
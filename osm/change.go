@@ -0,0 +1,160 @@
+package osm
+
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+
+	"github.com/godfried/osmimport/osm/overpass"
+	"github.com/godfried/osmimport/poi"
+)
+
+// OSMChange is an osmChange 0.6 document, as accepted by JOSM and the OSM API diff upload.
+type OSMChange struct {
+	XMLName   xml.Name     `xml:"osmChange"`
+	Version   string       `xml:"version,attr"`
+	Generator string       `xml:"generator,attr"`
+	Create    *ChangeBlock `xml:"create,omitempty"`
+	Modify    *ChangeBlock `xml:"modify,omitempty"`
+	Delete    *ChangeBlock `xml:"delete,omitempty"`
+}
+
+type ChangeBlock struct {
+	Node     []*Node
+	Elements []*overpass.Element
+}
+
+func NewOSMChange(create []poi.POI, modify, delete []*overpass.Element) *OSMChange {
+	o := &OSMChange{
+		Version:   "0.6",
+		Generator: "JOSM",
+	}
+	if len(create) > 0 {
+		o.Create = &ChangeBlock{Node: make([]*Node, 0, len(create))}
+		for i, p := range SortNew(create) {
+			o.Create.Node = append(o.Create.Node, NewNode(p, -1*(i+1)))
+		}
+	}
+	if len(modify) > 0 {
+		o.Modify = &ChangeBlock{Elements: sortElements(modify, false)}
+	}
+	if len(delete) > 0 {
+		o.Delete = &ChangeBlock{Elements: sortElements(delete, true)}
+	}
+	return o
+}
+
+// SetChangeset assigns changeset id to every element in the document.
+func (o *OSMChange) SetChangeset(id uint64) {
+	for _, b := range []*ChangeBlock{o.Create, o.Modify, o.Delete} {
+		if b == nil {
+			continue
+		}
+		for _, n := range b.Node {
+			n.Changeset = id
+		}
+		for _, e := range b.Elements {
+			e.Changeset = id
+		}
+	}
+}
+
+// Len returns the number of elements created, modified and deleted by the document.
+func (o *OSMChange) Len() int {
+	n := 0
+	for _, b := range []*ChangeBlock{o.Create, o.Modify, o.Delete} {
+		if b != nil {
+			n += len(b.Node) + len(b.Elements)
+		}
+	}
+	return n
+}
+
+// sortElements orders elements so that members are handled before their parents, or after them when reverse is set,
+// as is required for deletions, and by ID within each type so that the same change is always written the same way.
+func sortElements(es []*overpass.Element, reverse bool) []*overpass.Element {
+	sorted := make([]*overpass.Element, len(es))
+	copy(sorted, es)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return elementLess(sorted[i], sorted[j], reverse)
+	})
+	return sorted
+}
+
+func elementLess(a, b *overpass.Element, reverse bool) bool {
+	at, bt := overpass.TypeOrder(a.Type), overpass.TypeOrder(b.Type)
+	if at != bt {
+		if reverse {
+			return at > bt
+		}
+		return at < bt
+	}
+	return a.ID < b.ID
+}
+
+// sortPOIs orders the OSM elements among pois as sortElements does, ahead of any other POIs, which are ordered as
+// SortNew orders them.
+func sortPOIs(pois []poi.POI) []poi.POI {
+	sorted := make([]poi.POI, len(pois))
+	copy(sorted, pois)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, aok := sorted[i].(*overpass.Element)
+		b, bok := sorted[j].(*overpass.Element)
+		switch {
+		case aok && bok:
+			return elementLess(a, b, false)
+		case aok || bok:
+			return aok
+		}
+		return newLess(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func sourceID(p poi.POI) (string, bool) {
+	if id, ok := p.(poi.Identified); ok {
+		return id.SourceID()
+	}
+	return "", false
+}
+
+// SortNew returns a copy of pois in the order GenerateXML and NewOSMChange number them, so that the same POIs are
+// always written the same way however they were read: by source ID, numerically if both are numbers, then by latitude
+// and longitude. POIs without a source ID, including those which are not poi.Identified, follow those with one.
+func SortNew(pois []poi.POI) []poi.POI {
+	sorted := make([]poi.POI, len(pois))
+	copy(sorted, pois)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return newLess(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func newLess(a, b poi.POI) bool {
+	aID, aok := sourceID(a)
+	bID, bok := sourceID(b)
+	if aok != bok {
+		return aok
+	}
+	if aID != bID {
+		an, aerr := strconv.ParseInt(aID, 10, 64)
+		bn, berr := strconv.ParseInt(bID, 10, 64)
+		if aerr == nil && berr == nil {
+			return an < bn
+		}
+		return aID < bID
+	}
+	if a.Latitude() != b.Latitude() {
+		return a.Latitude() < b.Latitude()
+	}
+	return a.Longitude() < b.Longitude()
+}
+
+func GenerateChangeXML(create []poi.POI, modify, delete []*overpass.Element, outFile string) error {
+	o := NewOSMChange(create, modify, delete)
+	data, err := xml.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(outFile, data)
+}
@@ -0,0 +1,99 @@
+package osm
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godfried/osmimport/osm/overpass"
+)
+
+func TestGenerateChangeXML(t *testing.T) {
+	modify := []*overpass.Element{
+		{Type: "way", ID: 20, Version: 2, Changeset: 7, Nodes: []uint64{1, 2}, TagMap: map[string]string{"sagns_id": "5"}},
+		{Type: "node", ID: 1, Version: 3, Lat: -33.1, Lon: 20.1, TagMap: map[string]string{"natural": "peak"}},
+	}
+	del := []*overpass.Element{
+		{Type: "node", ID: 2, Version: 1, Lat: -33.2, Lon: 20.2},
+		{Type: "relation", ID: 30, Version: 4},
+	}
+	out := filepath.Join(t.TempDir(), "out.osc")
+	err := GenerateChangeXML(testPOIs, modify, del, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"osmChange"`
+		Create  struct {
+			Nodes []Node `xml:"node"`
+		} `xml:"create"`
+		Modify struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"modify"`
+		Delete struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"delete"`
+	}
+	err = xml.Unmarshal(data, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Create.Nodes) != 2 || doc.Create.Nodes[0].ID != -1 || doc.Create.Nodes[1].ID != -2 {
+		t.Errorf("unexpected created nodes: %#v", doc.Create.Nodes)
+	}
+	if !strings.Contains(doc.Modify.Inner, `<way id="20" version="2" changeset="7">`) {
+		t.Errorf("modify block missing way: %s", doc.Modify.Inner)
+	}
+	if strings.Index(doc.Modify.Inner, `<node id="1"`) > strings.Index(doc.Modify.Inner, `<way id="20"`) {
+		t.Errorf("modified nodes should precede ways: %s", doc.Modify.Inner)
+	}
+	if strings.Index(doc.Delete.Inner, `<relation id="30"`) > strings.Index(doc.Delete.Inner, `<node id="2"`) {
+		t.Errorf("deleted relations should precede nodes: %s", doc.Delete.Inner)
+	}
+}
+
+func TestOSMChangeSetChangeset(t *testing.T) {
+	o := NewOSMChange(testPOIs, []*overpass.Element{{Type: "node", ID: 1}}, nil)
+	o.SetChangeset(42)
+	if o.Create.Node[0].Changeset != 42 || o.Modify.Elements[0].Changeset != 42 {
+		t.Errorf("changeset not set: %#v %#v", o.Create.Node[0], o.Modify.Elements[0])
+	}
+	if o.Delete != nil {
+		t.Errorf("empty delete block: %#v", o.Delete)
+	}
+}
+
+func TestNewOSMChangeEmpty(t *testing.T) {
+	if o := NewOSMChange(nil, nil, nil); o.Create != nil || o.Modify != nil || o.Delete != nil {
+		t.Errorf("empty change has blocks: %#v", o)
+	}
+}
+
+func TestOSMChangeLen(t *testing.T) {
+	tests := []struct {
+		name   string
+		change *OSMChange
+		want   int
+	}{
+		{"empty", NewOSMChange(nil, nil, nil), 0},
+		{"create only", NewOSMChange(testPOIs, nil, nil), 2},
+		{
+			"all blocks",
+			NewOSMChange(testPOIs, []*overpass.Element{{Type: "node", ID: 1}}, []*overpass.Element{{Type: "way", ID: 2}, {Type: "node", ID: 3}}),
+			5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.change.Len(); got != tt.want {
+				t.Errorf("Len() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
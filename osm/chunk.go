@@ -0,0 +1,198 @@
+package osm
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+// ChunkOptions controls how GenerateChunkedXML splits POIs into files, which by the import guidelines should be small
+// enough to be reviewed and uploaded as a single changeset.
+type ChunkOptions struct {
+	// MaxElements is the most POIs written to a file, there is no limit if it is 0.
+	MaxElements int
+	// TileDegrees is the size of the grid cells POIs are grouped by before being split by MaxElements, they are not
+	// grouped if it is 0.
+	TileDegrees float64
+	// Tile names the tile, such as a district, of a POI, taking precedence over TileDegrees if set.
+	Tile func(p poi.POI) string
+}
+
+// Enabled reports whether o splits POIs at all.
+func (o ChunkOptions) Enabled() bool {
+	return o.MaxElements > 0 || o.TileDegrees > 0 || o.Tile != nil
+}
+
+func (o ChunkOptions) tile(p poi.POI) string {
+	if o.Tile != nil {
+		return o.Tile(p)
+	}
+	if o.TileDegrees <= 0 {
+		return ""
+	}
+	// Tiles are named after their south west corners, rounded to hide floating point error.
+	corner := func(f float64) string {
+		return formatFloat(math.Round(math.Floor(f/o.TileDegrees)*o.TileDegrees*1e6) / 1e6)
+	}
+	return corner(p.Latitude()) + "_" + corner(p.Longitude())
+}
+
+// Chunk is a group of POIs written to a file of their own.
+type Chunk struct {
+	// Tile is the tile the POIs are in, empty if they were not grouped by tile.
+	Tile string
+	// Part numbers the chunks of a tile from 1.
+	Part int
+	File string
+	POIs []poi.POI
+	// Bounds encloses the POIs.
+	Bounds *Bounds
+}
+
+// SplitChunks groups pois by tile, sorted by name, and splits the groups into chunks of at most MaxElements POIs,
+// keeping the order of pois within each tile.
+func SplitChunks(pois []poi.POI, opts ChunkOptions) []*Chunk {
+	tiles := make(map[string][]poi.POI)
+	names := make([]string, 0)
+	for _, p := range pois {
+		t := opts.tile(p)
+		if _, ok := tiles[t]; !ok {
+			names = append(names, t)
+		}
+		tiles[t] = append(tiles[t], p)
+	}
+	sort.Strings(names)
+	chunks := make([]*Chunk, 0, len(names))
+	for _, t := range names {
+		tilePOIs := tiles[t]
+		size := opts.MaxElements
+		if size <= 0 {
+			size = len(tilePOIs)
+		}
+		for start, part := 0, 1; start < len(tilePOIs); start, part = start+size, part+1 {
+			end := start + size
+			if end > len(tilePOIs) {
+				end = len(tilePOIs)
+			}
+			c := &Chunk{Tile: t, Part: part, POIs: tilePOIs[start:end], Bounds: NewBounds()}
+			for _, p := range c.POIs {
+				c.Bounds.Expand(p)
+			}
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks
+}
+
+// GenerateChunkedXML writes pois, sorted by SortNew and split by SplitChunks, to a file per chunk, named after outFile
+// with the tile and part inserted before its extension, and lists the chunks in an index file named after outFile with
+// -index.csv in place of its extension. If opts do not split pois it writes them all to outFile, as GenerateXML does,
+// without an index.
+func GenerateChunkedXML(pois []poi.POI, opts ChunkOptions, outFile string) ([]*Chunk, error) {
+	if !opts.Enabled() {
+		c := &Chunk{Part: 1, File: outFile, POIs: pois, Bounds: NewBounds()}
+		for _, p := range pois {
+			c.Bounds.Expand(p)
+		}
+		return []*Chunk{c}, GenerateXML(pois, outFile)
+	}
+	base, ext := splitExt(outFile)
+	// Sorting first keeps the same POIs in the same chunks however they were read.
+	chunks := SplitChunks(SortNew(pois), opts)
+	tileFiles := newTileFiles()
+	for _, c := range chunks {
+		name := base
+		if c.Tile != "" {
+			name += "-" + tileFiles.name(c.Tile)
+		}
+		c.File = fmt.Sprintf("%s-%d%s", name, c.Part, ext)
+		err := GenerateXML(c.POIs, c.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chunks, writeChunkIndex(chunks, base+"-index.csv")
+}
+
+// splitExt splits outFile into the path before its extension and the extension, including any compression suffix, so
+// out.osm.gz gives out and .osm.gz.
+func splitExt(outFile string) (string, string) {
+	base, compression := outFile, ""
+	for _, suffix := range []string{".gz", ".bz2"} {
+		if strings.HasSuffix(outFile, suffix) {
+			base, compression = strings.TrimSuffix(outFile, suffix), suffix
+			break
+		}
+	}
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext), ext + compression
+}
+
+// fileSafe replaces the characters of a tile name, which may be a district or anything else Tile returns, that do not
+// belong in a file name with underscores.
+func fileSafe(tile string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, tile)
+}
+
+// tileFiles gives each tile a distinct file safe name, as different tiles, such as a/b and a_b, can have the same
+// fileSafe name.
+type tileFiles struct {
+	names map[string]string
+	used  map[string]bool
+}
+
+func newTileFiles() *tileFiles {
+	return &tileFiles{names: make(map[string]string), used: make(map[string]bool)}
+}
+
+// name returns the file safe name of tile, numbering it if another tile already has the same name.
+func (tf *tileFiles) name(tile string) string {
+	if name, ok := tf.names[tile]; ok {
+		return name
+	}
+	safe := fileSafe(tile)
+	name := safe
+	for n := 2; tf.used[name]; n++ {
+		name = safe + "_" + strconv.Itoa(n)
+	}
+	tf.names[tile], tf.used[name] = name, true
+	return name
+}
+
+// writeChunkIndex lists chunks in a CSV file, with their files relative to it, for reviewers to claim and tick off.
+func writeChunkIndex(chunks []*Chunk, indexFile string) error {
+	f, err := createFile(indexFile)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"file", "tile", "part", "elements", "minlat", "minlon", "maxlat", "maxlon"})
+	for _, c := range chunks {
+		file, err := filepath.Rel(filepath.Dir(indexFile), c.File)
+		if err != nil {
+			file = c.File
+		}
+		w.Write([]string{
+			file, c.Tile, strconv.Itoa(c.Part), strconv.Itoa(len(c.POIs)),
+			formatFloat(c.Bounds.MinLat), formatFloat(c.Bounds.MinLon), formatFloat(c.Bounds.MaxLat), formatFloat(c.Bounds.MaxLon),
+		})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,153 @@
+package osm
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+func chunkPOIs() []poi.POI {
+	return []poi.POI{
+		testPOI{lat: -33.9626, lon: 18.4039, tags: map[string]string{"name": "a"}},
+		testPOI{lat: -33.9355, lon: 18.3892, tags: map[string]string{"name": "b"}},
+		testPOI{lat: -34.3568, lon: 18.4740, tags: map[string]string{"name": "c"}},
+		testPOI{lat: -33.9500, lon: 18.4100, tags: map[string]string{"name": "d"}},
+		testPOI{lat: -33.4, lon: 22.05, tags: map[string]string{"name": "e"}},
+	}
+}
+
+func chunkNames(chunks []*Chunk) [][]string {
+	names := make([][]string, 0, len(chunks))
+	for _, c := range chunks {
+		ns := make([]string, 0, len(c.POIs))
+		for _, p := range c.POIs {
+			ns = append(ns, p.String())
+		}
+		names = append(names, ns)
+	}
+	return names
+}
+
+func TestSplitChunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  ChunkOptions
+		tiles []string
+		want  [][]string
+	}{
+		{"none", ChunkOptions{}, []string{""}, [][]string{{"a", "b", "c", "d", "e"}}},
+		{"max elements", ChunkOptions{MaxElements: 2}, []string{"", "", ""}, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{"grid", ChunkOptions{TileDegrees: 0.5}, []string{"-33.5_22", "-34.5_18", "-34_18"}, [][]string{{"e"}, {"c"}, {"a", "b", "d"}}},
+		{"grid and max elements", ChunkOptions{TileDegrees: 0.5, MaxElements: 2}, []string{"-33.5_22", "-34.5_18", "-34_18", "-34_18"}, [][]string{{"e"}, {"c"}, {"a", "b"}, {"d"}}},
+		{"fine grid", ChunkOptions{TileDegrees: 0.1}, []string{"-33.4_22", "-34.4_18.4", "-34_18.3", "-34_18.4"}, [][]string{{"e"}, {"c"}, {"b"}, {"a", "d"}}},
+		{"named tiles", ChunkOptions{TileDegrees: 1, Tile: func(p poi.POI) string {
+			if p.Longitude() > 20 {
+				return "karoo"
+			}
+			return "cape town"
+		}}, []string{"cape town", "karoo"}, [][]string{{"a", "b", "c", "d"}, {"e"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitChunks(chunkPOIs(), tt.opts)
+			tiles := make([]string, 0, len(chunks))
+			for _, c := range chunks {
+				tiles = append(tiles, c.Tile)
+			}
+			if !reflect.DeepEqual(tiles, tt.tiles) {
+				t.Errorf("tiles = %q, want %q", tiles, tt.tiles)
+			}
+			if got := chunkNames(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateChunkedXML(t *testing.T) {
+	dir := t.TempDir()
+	chunks, err := GenerateChunkedXML(chunkPOIs(), ChunkOptions{TileDegrees: 0.5, MaxElements: 2}, filepath.Join(dir, "out.osm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(chunks))
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "out--34_18-1.osm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(OSM)
+	if err := xml.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Node) != 2 || got.Node[0].ID != -1 || got.Node[1].ID != -2 {
+		t.Errorf("chunk has nodes %v, want -1 and -2", got.Node)
+	}
+	if got.Bounds.MinLat != -33.9626 || got.Bounds.MaxLat != -33.95 || got.Bounds.MinLon != 18.4039 || got.Bounds.MaxLon != 18.41 {
+		t.Errorf("chunk bounds = %+v, want those of a and d", got.Bounds)
+	}
+	index, err := ioutil.ReadFile(filepath.Join(dir, "out-index.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `file,tile,part,elements,minlat,minlon,maxlat,maxlon
+out--33.5_22-1.osm,-33.5_22,1,1,-33.4,22.05,-33.4,22.05
+out--34.5_18-1.osm,-34.5_18,1,1,-34.3568,18.474,-34.3568,18.474
+out--34_18-1.osm,-34_18,1,2,-33.9626,18.4039,-33.95,18.41
+out--34_18-2.osm,-34_18,2,1,-33.9355,18.3892,-33.9355,18.3892
+`
+	if string(index) != want {
+		t.Errorf("index =\n%s\nwant\n%s", index, want)
+	}
+}
+
+func TestGenerateChunkedXMLFileNames(t *testing.T) {
+	named := func(p poi.POI) string {
+		if p.Longitude() > 20 {
+			return "Central Karoo/Beaufort West"
+		}
+		return "cape town"
+	}
+	colliding := func(p poi.POI) string {
+		if p.Longitude() > 20 {
+			return "a/b"
+		}
+		return "a_b"
+	}
+	tests := []struct {
+		name    string
+		opts    ChunkOptions
+		outFile string
+		want    []string
+	}{
+		{"single file", ChunkOptions{}, "out.osm.gz", []string{"out.osm.gz"}},
+		{"gzip", ChunkOptions{MaxElements: 3}, "out.osm.gz", []string{"out-1.osm.gz", "out-2.osm.gz", "out-index.csv"}},
+		{"bzip2", ChunkOptions{MaxElements: 3}, "out.osm.bz2", []string{"out-1.osm.bz2", "out-2.osm.bz2", "out-index.csv"}},
+		{"named tiles", ChunkOptions{Tile: named}, "out.osm", []string{"out-Central_Karoo_Beaufort_West-1.osm", "out-cape_town-1.osm", "out-index.csv"}},
+		{"colliding tiles", ChunkOptions{Tile: colliding}, "out.osm", []string{"out-a_b-1.osm", "out-a_b_2-1.osm", "out-index.csv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := GenerateChunkedXML(chunkPOIs(), tt.opts, filepath.Join(dir, tt.outFile)); err != nil {
+				t.Fatal(err)
+			}
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(files))
+			for _, f := range files {
+				got = append(got, f.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
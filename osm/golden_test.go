@@ -0,0 +1,113 @@
+package osm
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/godfried/osmimport/osm/overpass"
+	"github.com/godfried/osmimport/poi"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenPOIs have enough tags that writing them in map order would almost never match the golden files.
+func goldenPOIs() []poi.POI {
+	return []poi.POI{
+		testPOI{lat: -33.9626, lon: 18.4039, id: "101", tags: map[string]string{
+			"name": "Table Mountain", "name:af": "Tafelberg", "natural": "peak", "ele": "1085", "source": "sagns",
+			"sagns_id": "101", "wikidata": "Q213360", "prominence": "1085", "description": "table mountain",
+		}},
+		testPOI{lat: -33.9355, lon: 18.3892, id: "102", tags: map[string]string{
+			"name": "Lion's Head", "name:af": "Leeukop", "natural": "peak", "ele": "669", "source": "sagns", "sagns_id": "102",
+		}},
+		testPOI{lat: -34.3568, lon: 18.4740, id: "99", tags: map[string]string{
+			"name": "Cape Point", "natural": "cape", "source": "sagns", "sagns_id": "99",
+		}},
+		testPOI{lat: -33.4, lon: 22.05, tags: map[string]string{"name": "Swartberg", "natural": "peak"}},
+		testPOI{lat: -33.95, lon: 18.41, tags: map[string]string{"name": "Platteklip", "natural": "peak"}},
+	}
+}
+
+// shuffledPOIs returns goldenPOIs in a random order, which must not change what is written.
+func shuffledPOIs() []poi.POI {
+	pois := goldenPOIs()
+	rand.Shuffle(len(pois), func(i, j int) { pois[i], pois[j] = pois[j], pois[i] })
+	return pois
+}
+
+func goldenElements() []*overpass.Element {
+	return []*overpass.Element{
+		{Type: "way", ID: 20, Version: 2, Nodes: []uint64{3, 1, 2}, TagMap: map[string]string{"natural": "water", "water": "lake", "name": "Bow Lake", "sagns_id": "103", "source": "sagns"}},
+		{Type: "node", ID: 9, Version: 1, Lat: -33.5, Lon: 19.2, TagMap: map[string]string{"natural": "peak", "name": "Sneeukop", "ele": "1930", "sagns_id": "104"}},
+		{Type: "relation", ID: 30, Version: 4, Members: []overpass.Member{{Type: "way", Ref: 20, Role: "outer"}}, TagMap: map[string]string{"type": "multipolygon", "natural": "water", "sagns_id": "105"}},
+		{Type: "node", ID: 5, Version: 3, Lat: -33.1, Lon: 20.1, TagMap: map[string]string{"natural": "peak", "name": "Matroosberg", "ele": "2249", "sagns_id": "106", "source": "sagns"}},
+	}
+}
+
+// checkGolden compares the file written by generate with testdata/name, rewriting it instead if -update is set. The
+// file is generated repeatedly, as a single run could happen to come out in the right order.
+func checkGolden(t *testing.T, name string, generate func(outFile string) error) {
+	golden := filepath.Join("testdata", name)
+	for i := 0; i < 10; i++ {
+		out := filepath.Join(t.TempDir(), name)
+		if err := generate(out); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("run %d of %s differs from %s:\n%s", i, name, golden, got)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	t.Run("GenerateXML", func(t *testing.T) {
+		checkGolden(t, "generate.osm", func(out string) error {
+			return GenerateXML(goldenPOIs(), out)
+		})
+	})
+	t.Run("GenerateXML shuffled", func(t *testing.T) {
+		checkGolden(t, "generate.osm", func(out string) error {
+			return GenerateXML(shuffledPOIs(), out)
+		})
+	})
+	t.Run("GenerateUpdateXML", func(t *testing.T) {
+		checkGolden(t, "update.osm", func(out string) error {
+			es := goldenElements()
+			pois := make([]poi.POI, 0, len(es))
+			for _, e := range es {
+				pois = append(pois, e)
+			}
+			return GenerateUpdateXML(pois, out)
+		})
+	})
+	t.Run("GenerateChangeXML", func(t *testing.T) {
+		checkGolden(t, "change.osc", func(out string) error {
+			es := goldenElements()
+			return GenerateChangeXML(goldenPOIs(), es[:2], es[2:], out)
+		})
+	})
+	t.Run("GenerateChangeXML shuffled", func(t *testing.T) {
+		checkGolden(t, "change.osc", func(out string) error {
+			es := goldenElements()
+			return GenerateChangeXML(shuffledPOIs(), es[:2], es[2:], out)
+		})
+	})
+}
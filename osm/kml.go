@@ -0,0 +1,230 @@
+package osm
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+// ReviewStatus is how a POI is drawn when exported to KML for review.
+type ReviewStatus int
+
+const (
+	// ReviewNew is a POI which will be added to OSM.
+	ReviewNew ReviewStatus = iota
+	// ReviewFixme is a POI which needs checking against existing OSM elements.
+	ReviewFixme
+	// ReviewMatched is a POI already in OSM.
+	ReviewMatched
+)
+
+var reviewStatuses = []ReviewStatus{ReviewNew, ReviewFixme, ReviewMatched}
+
+func (s ReviewStatus) String() string {
+	switch s {
+	case ReviewNew:
+		return "new"
+	case ReviewFixme:
+		return "fixme"
+	case ReviewMatched:
+		return "matched"
+	}
+	return fmt.Sprintf("ReviewStatus(%d)", int(s))
+}
+
+func (s ReviewStatus) icon() string {
+	switch s {
+	case ReviewFixme:
+		return "http://maps.google.com/mapfiles/kml/paddle/ylw-circle.png"
+	case ReviewMatched:
+		return "http://maps.google.com/mapfiles/kml/paddle/blu-circle.png"
+	}
+	return "http://maps.google.com/mapfiles/kml/paddle/grn-circle.png"
+}
+
+// ReviewPOI is a POI exported for review along with its status and an optional note shown in its balloon.
+type ReviewPOI struct {
+	POI    poi.POI
+	Status ReviewStatus
+	Note   string
+}
+
+// POIStatus returns ReviewFixme for POIs tagged with a fixme and ReviewNew for the rest.
+func POIStatus(p poi.POI) ReviewStatus {
+	if _, ok := p.Tags()["fixme"]; ok {
+		return ReviewFixme
+	}
+	return ReviewNew
+}
+
+// ReportStatus returns the review status of a conflated POI, ReviewMatched for exact matches and ReviewFixme for
+// probable and conflicting ones.
+func ReportStatus(m *poi.Match) ReviewStatus {
+	switch m.Status {
+	case poi.StatusExact:
+		return ReviewMatched
+	case poi.StatusProbable, poi.StatusConflict:
+		return ReviewFixme
+	}
+	return POIStatus(m.Source)
+}
+
+// GenerateKML writes pois to a KML or, if outFile ends in .kmz, KMZ file, styled by POIStatus.
+func GenerateKML(pois []poi.POI, outFile string) error {
+	review := make([]ReviewPOI, 0, len(pois))
+	for _, p := range pois {
+		review = append(review, ReviewPOI{POI: p, Status: POIStatus(p)})
+	}
+	return GenerateReviewKML(review, outFile)
+}
+
+// GenerateReportKML writes the source POIs of report to a KML or KMZ file, styled by ReportStatus with the reasons for
+// the match in their balloons.
+func GenerateReportKML(report poi.Report, outFile string) error {
+	review := make([]ReviewPOI, 0, len(report))
+	for _, m := range report {
+		note := m.Status.String()
+		if len(m.Reasons) > 0 {
+			note += ": " + strings.Join(m.Reasons, ", ")
+		}
+		review = append(review, ReviewPOI{POI: m.Source, Status: ReportStatus(m), Note: note})
+	}
+	return GenerateReviewKML(review, outFile)
+}
+
+// GenerateReviewKML writes pois to a KML or, if outFile ends in .kmz, KMZ file named after outFile.
+func GenerateReviewKML(pois []ReviewPOI, outFile string) error {
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(outFile), filepath.Ext(outFile))
+	if strings.EqualFold(filepath.Ext(outFile), ".kmz") {
+		err = writeKMZ(f, name, pois)
+	} else {
+		err = WriteKML(f, name, pois)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeKMZ(w io.Writer, name string, pois []ReviewPOI) error {
+	zw := zip.NewWriter(w)
+	doc, err := zw.Create("doc.kml")
+	if err != nil {
+		return err
+	}
+	err = WriteKML(doc, name, pois)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+type kmlDocument struct {
+	XMLName xml.Name    `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Name    string      `xml:"Document>name"`
+	Styles  []kmlStyle  `xml:"Document>Style"`
+	Folders []kmlFolder `xml:"Document>Folder"`
+}
+
+type kmlStyle struct {
+	ID   string `xml:"id,attr"`
+	Icon string `xml:"IconStyle>Icon>href"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	StyleURL    string `xml:"styleUrl"`
+	Coordinates string `xml:"Point>coordinates"`
+}
+
+// WriteKML writes pois to w as a KML document called name, with a folder and style for each status.
+func WriteKML(w io.Writer, name string, pois []ReviewPOI) error {
+	doc := kmlDocument{Name: name}
+	folders := make(map[ReviewStatus]*kmlFolder, len(reviewStatuses))
+	for _, s := range reviewStatuses {
+		doc.Styles = append(doc.Styles, kmlStyle{ID: s.String(), Icon: s.icon()})
+		folders[s] = &kmlFolder{Name: s.String()}
+	}
+	for _, p := range pois {
+		f, ok := folders[p.Status]
+		if !ok {
+			return fmt.Errorf("unknown review status %s of %s", p.Status, placemarkName(p.POI))
+		}
+		f.Placemarks = append(f.Placemarks, kmlPlacemark{
+			Name:        placemarkName(p.POI),
+			Description: balloon(p),
+			StyleURL:    "#" + p.Status.String(),
+			Coordinates: formatFloat(p.POI.Longitude()) + "," + formatFloat(p.POI.Latitude()),
+		})
+	}
+	for _, s := range reviewStatuses {
+		if len(folders[s].Placemarks) > 0 {
+			doc.Folders = append(doc.Folders, *folders[s])
+		}
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func placemarkName(p poi.POI) string {
+	for _, n := range p.Names() {
+		if n.Value != "" {
+			return n.Value
+		}
+	}
+	return p.Tags()["ref"]
+}
+
+// balloon renders the tags of p, sorted by key, as an HTML table below its note.
+func balloon(p ReviewPOI) string {
+	tags := p.POI.Tags()
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	if p.Note != "" {
+		fmt.Fprintf(&b, "<p>%s</p>", html.EscapeString(p.Note))
+	}
+	b.WriteString("<table>")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td></tr>", html.EscapeString(k), html.EscapeString(tags[k]))
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
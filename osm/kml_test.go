@@ -0,0 +1,77 @@
+package osm
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources/kml"
+)
+
+func TestWriteKML(t *testing.T) {
+	fixme := testPOI{lat: -33.9, lon: 18.4, tags: map[string]string{"name": "Devil's Peak", "fixme": "check <name>"}}
+	review := []ReviewPOI{
+		{POI: testPOIs[0], Status: ReviewMatched},
+		{POI: testPOIs[1], Status: ReviewNew, Note: "new & unmatched"},
+		{POI: fixme, Status: POIStatus(fixme)},
+	}
+	var b bytes.Buffer
+	if err := WriteKML(&b, "review", review); err != nil {
+		t.Fatal(err)
+	}
+	placemarks, err := kml.ReadAll(kml.NewReader(&b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name, folder, style string
+	}{
+		{"Lion's Head", "new", "#new"},
+		{"Devil's Peak", "fixme", "#fixme"},
+		{"Table Mountain", "matched", "#matched"},
+	}
+	if len(placemarks) != len(want) {
+		t.Fatalf("wrote %d placemarks, want %d", len(placemarks), len(want))
+	}
+	for i, w := range want {
+		p := placemarks[i]
+		if p.Name != w.name || !reflect.DeepEqual(p.Folders, []string{"review", w.folder}) || p.StyleURL != w.style {
+			t.Errorf("placemark %d = %s in %v styled %s, want %s in %s styled %s", i, p.Name, p.Folders, p.StyleURL, w.name, w.folder, w.style)
+		}
+	}
+	if got := placemarks[1].Fields(); !reflect.DeepEqual(got, fixme.tags) {
+		t.Errorf("balloon fields = %v, want %v", got, fixme.tags)
+	}
+	lat, lon, err := placemarks[2].Point.LatLon()
+	if err != nil || lat != -33.9626 || lon != 18.4039 {
+		t.Errorf("coordinates = %v,%v (%v), want -33.9626,18.4039", lat, lon, err)
+	}
+}
+
+func TestGenerateReportKMZ(t *testing.T) {
+	report := poi.Report{
+		{Source: testPOIs[0], Status: poi.StatusExact},
+		{Source: testPOIs[1], Status: poi.StatusProbable, Reasons: []string{"name similarity 0.80"}},
+	}
+	out := filepath.Join(t.TempDir(), "review.kmz")
+	if err := GenerateReportKML(report, out); err != nil {
+		t.Fatal(err)
+	}
+	r, err := kml.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	placemarks, err := kml.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placemarks) != 2 || placemarks[0].File != "doc.kml" {
+		t.Fatalf("read %d placemarks, want 2 from doc.kml", len(placemarks))
+	}
+	if placemarks[0].StyleURL != "#fixme" || placemarks[1].StyleURL != "#matched" {
+		t.Errorf("styles = %s, %s, want #fixme, #matched", placemarks[0].StyleURL, placemarks[1].StyleURL)
+	}
+}
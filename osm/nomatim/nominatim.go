@@ -8,31 +8,34 @@ import (
 	"time"
 )
 
+// BaseURL is the Nominatim instance queried for places.
+var BaseURL = "https://nominatim.openstreetmap.org"
+
 const (
-	nominatimFromID     = "https://nominatim.openstreetmap.org/reverse?format=json&osm_type=N&osm_id=%d&accept-language=en"
-	nominatimFromLatLon = "https://nominatim.openstreetmap.org/reverse?format=json&osm_type=N&lat=%f&lon=%f&accept-language=en"
+	nominatimFromID     = "%s/reverse?format=json&osm_type=N&osm_id=%d&accept-language=en"
+	nominatimFromLatLon = "%s/reverse?format=json&osm_type=N&lat=%f&lon=%f&accept-language=en"
 )
 
 type Place struct {
-	PlaceID     uint64
-	OSMType     string
-	OSMID       uint64
-	Lat         float64 `json:",string"`
-	Lon         float64 `json:",string"`
-	DisplayName string
-	Address     Address
-	BoundingBox []string
+	PlaceID     uint64   `json:"place_id"`
+	OSMType     string   `json:"osm_type"`
+	OSMID       uint64   `json:"osm_id"`
+	Lat         float64  `json:",string"`
+	Lon         float64  `json:",string"`
+	DisplayName string   `json:"display_name"`
+	Address     Address  `json:"address"`
+	BoundingBox []string `json:"boundingbox"`
 }
 
 type Address struct {
-	City          string
-	PostCode      string
-	Country       string
-	CountryCode   string
-	County        string
-	StateDistrict string
-	State         string
-	Peak          string
+	City          string `json:"city"`
+	PostCode      string `json:"postcode"`
+	Country       string `json:"country"`
+	CountryCode   string `json:"country_code"`
+	County        string `json:"county"`
+	StateDistrict string `json:"state_district"`
+	State         string `json:"state"`
+	Peak          string `json:"peak"`
 }
 
 func (a Address) String() string {
@@ -56,11 +59,11 @@ func (a Address) Area() string {
 }
 
 func PlaceFromNode(nodeID uint64) (*Place, error) {
-	return getPlace(fmt.Sprintf(nominatimFromID, nodeID))
+	return getPlace(fmt.Sprintf(nominatimFromID, BaseURL, nodeID))
 }
 
 func PlaceFromCoords(lat, lon float64) (*Place, error) {
-	return getPlace(fmt.Sprintf(nominatimFromLatLon, lat, lon))
+	return getPlace(fmt.Sprintf(nominatimFromLatLon, BaseURL, lat, lon))
 }
 
 func getPlace(nominatimURL string) (*Place, error) {
@@ -0,0 +1,86 @@
+package osm
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func withNominatim(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	old := BaseURL
+	BaseURL = srv.URL
+	t.Cleanup(func() {
+		BaseURL = old
+		srv.Close()
+	})
+}
+
+func TestPlaceFromNode(t *testing.T) {
+	fixture, err := ioutil.ReadFile("testdata/reverse.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/reverse" || r.URL.Query().Get("osm_id") != "1001" || r.URL.Query().Get("format") != "json" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write(fixture)
+	})
+	p, err := PlaceFromNode(1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PlaceID != 123456 || p.OSMID != 1001 || p.Lat != -33.9626 || p.Lon != 18.4039 {
+		t.Errorf("unexpected place %#v", p)
+	}
+	if p.Address.Peak != "Table Mountain" || p.Address.Area() != "Western Cape" {
+		t.Errorf("unexpected address %#v", p.Address)
+	}
+}
+
+func TestPlaceFromCoords(t *testing.T) {
+	withNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("lat") != "-33.962600" || q.Get("lon") != "18.403900" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"place_id": 1, "lat": "-33.9626", "lon": "18.4039", "address": {"city": "Cape Town"}}`))
+	})
+	p, err := PlaceFromCoords(-33.9626, 18.4039)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Address.Area() != "Cape Town" {
+		t.Errorf("Area() = %s, want Cape Town", p.Address.Area())
+	}
+}
+
+func TestPlaceError(t *testing.T) {
+	withNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	if _, err := PlaceFromNode(1); err == nil {
+		t.Error("expected error for bad status")
+	}
+}
+
+func TestAddressArea(t *testing.T) {
+	tests := []struct {
+		a    Address
+		want string
+	}{
+		{Address{State: "Western Cape", County: "Overberg"}, "Western Cape"},
+		{Address{County: "Overberg", City: "Hermanus"}, "Overberg"},
+		{Address{StateDistrict: "Namakwa"}, "Namakwa"},
+		{Address{City: "Cape Town"}, "Cape Town"},
+		{Address{}, "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Area(); got != tt.want {
+			t.Errorf("%s.Area() = %s, want %s", tt.a, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package overpass
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Cache stores Overpass JSON responses on disk, keyed by the normalised query.
+type Cache struct {
+	Dir string
+	// TTL is how long a cached response stays valid, zero meaning forever.
+	TTL time.Duration
+	// Refresh ignores cached responses, replacing them with fresh results.
+	Refresh bool
+}
+
+func NewCache(dir string, ttl time.Duration, refresh bool) *Cache {
+	return &Cache{Dir: dir, TTL: ttl, Refresh: refresh}
+}
+
+// normaliseQuery collapses whitespace so that differently formatted but identical queries share an entry. Quoted
+// strings are kept as they are, as the whitespace in them is part of the values searched for.
+func normaliseQuery(query string) string {
+	var b strings.Builder
+	var quote rune
+	escaped, space := false, false
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+		case unicode.IsSpace(c):
+			space = true
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (c *Cache) path(query string) string {
+	sum := sha256.Sum256([]byte(normaliseQuery(query)))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the cached response for query, if there is a valid one.
+func (c *Cache) Get(query string) ([]byte, bool) {
+	if c.Refresh {
+		return nil, false
+	}
+	p := c.path(query)
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, false
+	}
+	if c.TTL > 0 && time.Since(info.ModTime()) > c.TTL {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		log.Printf("could not read cached response %s: %s", p, err)
+		return nil, false
+	}
+	return data, true
+}
+
+func (c *Cache) Put(query string, data []byte) error {
+	err := os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return err
+	}
+	p := c.path(query)
+	tmp, err := ioutil.TempFile(c.Dir, filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// UseCache caches the responses of DefaultClient in dir.
+func UseCache(dir string, ttl time.Duration, refresh bool) {
+	DefaultClient.Cache = NewCache(dir, ttl, refresh)
+}
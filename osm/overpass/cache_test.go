@@ -0,0 +1,141 @@
+package overpass
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache(t.TempDir(), time.Hour, false)
+	if _, ok := c.Get("node(1); out;"); ok {
+		t.Fatal("empty cache returned a result")
+	}
+	err := c.Put("node(1); out;", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, ok := c.Get("node(1);\n\tout;")
+	if !ok || string(data) != "data" {
+		t.Errorf("Get() = %q, %t, want data for whitespace-equivalent query", data, ok)
+	}
+	if _, ok := c.Get("node(2); out;"); ok {
+		t.Error("Get() returned a result for a different query")
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	err = os.Chtimes(c.path("node(1); out;"), old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("node(1); out;"); ok {
+		t.Error("Get() returned an expired result")
+	}
+	c.TTL = 0
+	if _, ok := c.Get("node(1); out;"); !ok {
+		t.Error("Get() without TTL did not return result")
+	}
+	c.Refresh = true
+	if _, ok := c.Get("node(1); out;"); ok {
+		t.Error("Get() returned a result while refreshing")
+	}
+}
+
+func TestNormaliseQuery(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+		same       bool
+	}{
+		{"whitespace", "node(1);\n\tout;", "  node(1); out;  ", true},
+		{"outside quotes", `node[ "name"="A B" ];`, `node[ "name"="A B"  ];`, true},
+		{"inside double quotes", `node["name"="A  B"];`, `node["name"="A B"];`, false},
+		{"inside single quotes", `node['name'='A  B'];`, `node['name'='A B'];`, false},
+		{"escaped quote", `node["name"="A\"  B"];`, `node["name"="A\" B"];`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := normaliseQuery(tt.a), normaliseQuery(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("normaliseQuery(%q) = %q, normaliseQuery(%q) = %q, want same %t", tt.a, a, tt.b, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestCachePut(t *testing.T) {
+	c := NewCache(filepath.Join(t.TempDir(), "overpass", "cache"), 0, false)
+	for _, data := range []string{"old", "new"} {
+		if err := c.Put("q", []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, ok := c.Get("q"); !ok || string(data) != "new" {
+		t.Errorf("Get() = %q, %t, want the replaced result", data, ok)
+	}
+	files, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("cache holds %d files, want 1 without temporary files", len(files))
+	}
+}
+
+func TestClientCacheCorrupt(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"elements":[{"type":"node","id":1}]}`))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv.URL)
+	c.Cache = NewCache(t.TempDir(), 0, false)
+	if err := c.Cache.Put("q", []byte("{truncated")); err != nil {
+		t.Fatal(err)
+	}
+	es, err := c.RunQuery("q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || calls != 1 {
+		t.Errorf("got %d elements from %d requests, want 1 from 1", len(es), calls)
+	}
+	if data, _ := c.Cache.Get("q"); string(data) == "{truncated" {
+		t.Error("corrupt cache entry was not replaced")
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"elements":[{"type":"node","id":1}]}`))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv.URL)
+	c.Cache = NewCache(t.TempDir(), 0, false)
+	for i := 0; i < 3; i++ {
+		es, err := c.RunQuery("q")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(es) != 1 {
+			t.Fatalf("got %d elements, want 1", len(es))
+		}
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1", calls)
+	}
+	c.Cache.Refresh = true
+	_, err := c.RunQuery("q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("made %d requests after refresh, want 2", calls)
+	}
+}
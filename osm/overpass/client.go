@@ -0,0 +1,194 @@
+package overpass
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var endpoints = []string{
+	"https://lz4.overpass-api.de/api/interpreter",
+	"https://z.overpass-api.de/api/interpreter",
+	"https://overpass.kumi.systems/api/interpreter",
+}
+
+// Client runs queries against a list of Overpass endpoints, retrying with exponential backoff.
+type Client struct {
+	Endpoints   []string
+	HTTPClient  *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Cache       *Cache
+}
+
+// DefaultClient queries the public Overpass endpoints and is the default Backend.
+var DefaultClient = NewClient(endpoints...)
+
+func NewClient(endpoints ...string) *Client {
+	return &Client{
+		Endpoints:   endpoints,
+		HTTPClient:  &http.Client{Timeout: 180 * time.Second},
+		MaxAttempts: 30,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    2 * time.Minute,
+	}
+}
+
+// StatusError is returned when an endpoint responds with a non-200 status.
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("bad status code from %s: %s", e.Endpoint, e.Status)
+}
+
+func (e *StatusError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+func (c *Client) RunQuery(query string) ([]*Element, error) {
+	return c.RunQueryContext(context.Background(), query)
+}
+
+func (c *Client) RunQueryContext(ctx context.Context, query string) ([]*Element, error) {
+	if c.Cache != nil {
+		if body, ok := c.Cache.Get(query); ok {
+			log.Printf("using cached results for query %s", query)
+			es, err := decodeElements(body)
+			if err == nil {
+				return es, nil
+			}
+			log.Printf("could not decode cached results: %s", err)
+		}
+	}
+	body, err := c.fetch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("results: %s", body)
+	es, err := decodeElements(body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Cache != nil {
+		err = c.Cache.Put(query, body)
+		if err != nil {
+			log.Printf("could not cache results: %s", err)
+		}
+	}
+	return es, nil
+}
+
+func decodeElements(body []byte) ([]*Element, error) {
+	result := new(Result)
+	err := json.Unmarshal(body, result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Elements, nil
+}
+
+// fetch posts query to each endpoint in turn until one succeeds, the attempts run out or ctx is done.
+func (c *Client) fetch(ctx context.Context, query string) ([]byte, error) {
+	if len(c.Endpoints) == 0 {
+		return nil, fmt.Errorf("no Overpass endpoints configured")
+	}
+	log.Printf("running query %s", query)
+	attempts := c.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			delay := c.backoff(i, err)
+			log.Printf("query failed: %s, retrying in %s", err, delay)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		var body []byte
+		body, err = c.post(ctx, c.Endpoints[i%len(c.Endpoints)], query)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if se, ok := err.(*StatusError); ok && !se.temporary() {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (c *Client) post(ctx context.Context, endpoint, query string) ([]byte, error) {
+	vals := url.Values{"data": []string{query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(vals.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{
+			Endpoint:   endpoint,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// backoff returns the delay before the given attempt: exponential with full jitter, but never less than
+// a Retry-After requested by the server.
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	delay := c.MaxDelay
+	if shift := uint(attempt - 1); shift < 32 && c.BaseDelay<<shift < c.MaxDelay {
+		delay = c.BaseDelay << shift
+	}
+	if delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+	if se, ok := err.(*StatusError); ok && se.RetryAfter > delay {
+		delay = se.RetryAfter
+	}
+	return delay
+}
+
+func retryAfter(val string) time.Duration {
+	if val == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
@@ -0,0 +1,180 @@
+package overpass
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newTestClient(endpoints ...string) *Client {
+	c := NewClient(endpoints...)
+	c.BaseDelay = time.Millisecond
+	c.MaxDelay = 10 * time.Millisecond
+	c.MaxAttempts = 5
+	return c
+}
+
+func TestClientRunQuery(t *testing.T) {
+	fixture := readFixture(t, "result.json")
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		case 2:
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		if r.Method != http.MethodPost || r.FormValue("data") != "node(1);out;" {
+			t.Errorf("unexpected request %s %q", r.Method, r.FormValue("data"))
+		}
+		w.Write(fixture)
+	}))
+	defer srv.Close()
+	es, err := newTestClient(srv.URL).RunQuery("node(1);out;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 3 {
+		t.Errorf("got %d elements, want 3", len(es))
+	}
+	if calls != 3 {
+		t.Errorf("made %d requests, want 3", calls)
+	}
+}
+
+func TestClientFailsOver(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"elements":[{"type":"node","id":1}]}`))
+	}))
+	defer up.Close()
+	es, err := newTestClient(down.URL, up.URL).RunQuery("q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].ID != 1 {
+		t.Errorf("unexpected elements %v", es)
+	}
+}
+
+func TestClientDoesNotRetryBadQuery(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	_, err := newTestClient(srv.URL).RunQuery("q")
+	se, ok := err.(*StatusError)
+	if !ok || se.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want StatusError 400", err)
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1", calls)
+	}
+}
+
+func TestClientGivesUp(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	_, err := newTestClient(srv.URL).RunQuery("q")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 5 {
+		t.Errorf("made %d requests, want 5", calls)
+	}
+}
+
+func TestClientContextCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := newTestClient(srv.URL).RunQueryContext(ctx, "q")
+	if err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("cancellation did not interrupt Retry-After wait")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	if _, err := NewClient().RunQuery("q"); err == nil {
+		t.Error("expected error without endpoints")
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>not json</html>"))
+	}))
+	defer srv.Close()
+	if es, err := newTestClient(srv.URL).RunQuery("q"); err == nil {
+		t.Errorf("expected error decoding a non JSON response, got %v", es)
+	}
+}
+
+func TestClientBackoff(t *testing.T) {
+	c := NewClient()
+	c.BaseDelay = time.Second
+	c.MaxDelay = 10 * time.Second
+	for attempt := 1; attempt < 40; attempt++ {
+		limit := c.MaxDelay
+		if attempt < 4 {
+			limit = c.BaseDelay << uint(attempt-1)
+		}
+		if got := c.backoff(attempt, nil); got < 0 || got > limit {
+			t.Errorf("backoff(%d) = %s, want at most %s", attempt, got, limit)
+		}
+	}
+	retry := &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}
+	if got := c.backoff(1, retry); got != time.Minute {
+		t.Errorf("backoff() = %s, want the Retry-After of %s", got, retry.RetryAfter)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		val  string
+		want time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"garbage", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.val); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.val, got, tt.want)
+		}
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := retryAfter(future); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("retryAfter(%q) = %s, want about an hour", future, got)
+	}
+}
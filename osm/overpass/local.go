@@ -0,0 +1,275 @@
+package overpass
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+// Store is an in-memory OSM extract which answers Overpass queries locally.
+type Store struct {
+	nodes     map[uint64]*Element
+	ways      map[uint64]*Element
+	relations map[uint64]*Element
+	tagged    map[string][]*Element
+	index     *poi.Index
+}
+
+type xmlElement struct {
+	ID        uint64   `xml:"id,attr"`
+	Lat       float64  `xml:"lat,attr"`
+	Lon       float64  `xml:"lon,attr"`
+	Timestamp string   `xml:"timestamp,attr"`
+	Version   uint32   `xml:"version,attr"`
+	Changeset uint64   `xml:"changeset,attr"`
+	User      string   `xml:"user,attr"`
+	UID       uint64   `xml:"uid,attr"`
+	Tags      []xmlTag `xml:"tag"`
+	Nds       []xmlNd  `xml:"nd"`
+	Members   []Member `xml:"member"`
+}
+
+type xmlTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+type xmlNd struct {
+	Ref uint64 `xml:"ref,attr"`
+}
+
+func NewStore() *Store {
+	return &Store{
+		nodes:     make(map[uint64]*Element, 4096),
+		ways:      make(map[uint64]*Element, 1024),
+		relations: make(map[uint64]*Element, 64),
+		tagged:    make(map[string][]*Element, 3),
+		index:     poi.NewIndex(poi.DefaultCellSize),
+	}
+}
+
+// LoadFile reads an OSM XML extract, optionally gzip or bzip2 compressed.
+func LoadFile(osmFile string) (*Store, error) {
+	f, err := os.Open(osmFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(osmFile, ".pbf"):
+		return nil, fmt.Errorf("cannot load %s: PBF extracts are not supported, convert to .osm first", osmFile)
+	case strings.HasSuffix(osmFile, ".gz"):
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case strings.HasSuffix(osmFile, ".bz2"):
+		r = bzip2.NewReader(f)
+	}
+	s := NewStore()
+	err = s.Load(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load %s: %s", osmFile, err)
+	}
+	return s, nil
+}
+
+// Load adds all nodes, ways and relations in the OSM XML read from r to the store.
+func (s *Store) Load(r io.Reader) error {
+	d := xml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "node", "way", "relation":
+		default:
+			continue
+		}
+		x := new(xmlElement)
+		err = d.DecodeElement(x, &start)
+		if err != nil {
+			return err
+		}
+		s.Add(x.element(start.Name.Local))
+	}
+}
+
+func (x *xmlElement) element(tipe string) *Element {
+	e := &Element{
+		Type:      tipe,
+		ID:        x.ID,
+		Lat:       x.Lat,
+		Lon:       x.Lon,
+		Timestamp: x.Timestamp,
+		Version:   x.Version,
+		Changeset: x.Changeset,
+		User:      x.User,
+		UID:       x.UID,
+		Members:   x.Members,
+	}
+	if len(x.Tags) > 0 {
+		e.TagMap = make(map[string]string, len(x.Tags))
+		for _, t := range x.Tags {
+			e.TagMap[t.Key] = t.Value
+		}
+	}
+	if len(x.Nds) > 0 {
+		e.Nodes = make([]uint64, 0, len(x.Nds))
+		for _, n := range x.Nds {
+			e.Nodes = append(e.Nodes, n.Ref)
+		}
+	}
+	return e
+}
+
+// Add stores e, replacing the element of the same type and ID if there is one.
+func (s *Store) Add(e *Element) {
+	elements := s.elements(e.Type)
+	if elements == nil {
+		return
+	}
+	old := elements[e.ID]
+	elements[e.ID] = e
+	if e.Type == "node" {
+		if old != nil && len(old.TagMap) > 0 {
+			s.index.Remove(old)
+		}
+		if len(e.TagMap) > 0 {
+			s.index.Add(e)
+		}
+	}
+	tagged := s.tagged[e.Type]
+	if old != nil && len(old.TagMap) > 0 {
+		for i, t := range tagged {
+			if t != old {
+				continue
+			}
+			if len(e.TagMap) > 0 {
+				tagged[i] = e
+			} else {
+				s.tagged[e.Type] = append(tagged[:i], tagged[i+1:]...)
+			}
+			return
+		}
+	}
+	if len(e.TagMap) > 0 {
+		s.tagged[e.Type] = append(tagged, e)
+	}
+}
+
+func (s *Store) elements(tipe string) map[uint64]*Element {
+	switch tipe {
+	case "node":
+		return s.nodes
+	case "way":
+		return s.ways
+	case "relation":
+		return s.relations
+	}
+	return nil
+}
+
+// RunQuery evaluates query against the store. Only the subset of Overpass QL used by this project is supported.
+func (s *Store) RunQuery(query string) ([]*Element, error) {
+	stmts, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(stmts), nil
+}
+
+// geometry returns the nodes making up e, resolving way and relation members recursively.
+func (s *Store) geometry(e *Element, seen map[uint64]struct{}) []*Element {
+	switch e.Type {
+	case "node":
+		return []*Element{e}
+	case "way":
+		nodes := make([]*Element, 0, len(e.Nodes))
+		for _, id := range e.Nodes {
+			if n, ok := s.nodes[id]; ok {
+				nodes = append(nodes, n)
+			}
+		}
+		return nodes
+	case "relation":
+		if _, ok := seen[e.ID]; ok {
+			return nil
+		}
+		seen[e.ID] = struct{}{}
+		var nodes []*Element
+		for _, m := range e.Members {
+			if me, ok := s.elements(m.Type)[m.Ref]; ok {
+				nodes = append(nodes, s.geometry(me, seen)...)
+			}
+		}
+		return nodes
+	}
+	return nil
+}
+
+// recurseDown returns all members of the ways and relations in set, as Overpass's > statement does.
+func (s *Store) recurseDown(set []*Element) []*Element {
+	found := make(map[string]map[uint64]*Element, 3)
+	add := func(e *Element) bool {
+		if found[e.Type] == nil {
+			found[e.Type] = make(map[uint64]*Element)
+		}
+		if _, ok := found[e.Type][e.ID]; ok {
+			return false
+		}
+		found[e.Type][e.ID] = e
+		return true
+	}
+	var walk func(e *Element)
+	walk = func(e *Element) {
+		for _, id := range e.Nodes {
+			if n, ok := s.nodes[id]; ok {
+				add(n)
+			}
+		}
+		for _, m := range e.Members {
+			me, ok := s.elements(m.Type)[m.Ref]
+			if ok && add(me) {
+				walk(me)
+			}
+		}
+	}
+	for _, e := range set {
+		walk(e)
+	}
+	result := make([]*Element, 0, len(found["node"])+len(found["way"])+len(found["relation"]))
+	for _, es := range found {
+		for _, e := range es {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// UseLocalFile loads osmFile and answers all subsequent queries from it.
+func UseLocalFile(osmFile string) error {
+	s, err := LoadFile(osmFile)
+	if err != nil {
+		return err
+	}
+	UseBackend(s)
+	return nil
+}
@@ -0,0 +1,184 @@
+package overpass
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+func TestStoreRunQuery(t *testing.T) {
+	s, err := LoadFile("testdata/extract.osm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			"default query",
+			BuildQuery(DefaultQuery, []poi.Attribute{{Key: "natural", Value: "peak"}}, 5000, -33.4, 20.0),
+			[]string{"node/1", "node/2"},
+		},
+		{
+			"default query with small radius",
+			BuildQuery(DefaultQuery, []poi.Attribute{{Key: "natural", Value: "peak"}}, 500, -33.4, 20.0),
+			[]string{"node/1"},
+		},
+		{
+			"around matches way geometry and recurses down",
+			BuildQuery(SAGNSQuery, nil, 20000, -33.5, 20.1),
+			[]string{"way/10", "relation/20", "node/3", "node/4", "way/10"},
+		},
+		{
+			"bbox",
+			`[out:json];(way["sagns_id"](-34,18,-33,21);node["ref"](-34,18,-33,21););out meta;`,
+			[]string{"node/5", "way/10"},
+		},
+		{
+			"if condition",
+			"[out:json];\n(\n\tnode[natural=peak][name!=\"\"](around:5000,-33.4,20.0)\n\t(if:t[\"ele\"] > 1599); \n);\nout meta;",
+			[]string{"node/1"},
+		},
+		{
+			"regexp and negation",
+			`node["name"~"^Klein"][!"ref"];out;`,
+			[]string{"node/2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, err := s.RunQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(es))
+			for _, e := range es {
+				got = append(got, e.Type+"/"+formatID(e.ID))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreOutModes(t *testing.T) {
+	s, err := LoadFile("testdata/extract.osm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := s.RunQuery(`node(1);out meta;`)
+	if err == nil {
+		t.Errorf("expected error for unsupported query, got %v", es)
+	}
+	es, err = s.RunQuery(`way["sagns_id"="7"];out center meta;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].Center == nil || es[0].Version != 1 || es[0].TagMap["waterway"] != "river" {
+		t.Fatalf("unexpected result %#v", es)
+	}
+	if math.Abs(es[0].Latitude()+33.51) > 1e-9 || math.Abs(es[0].Longitude()-20.1) > 1e-9 {
+		t.Errorf("centre = %f,%f, want -33.51,20.1", es[0].Latitude(), es[0].Longitude())
+	}
+	es, err = s.RunQuery(`way["sagns_id"="7"];out skel;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].TagMap != nil || len(es[0].Nodes) != 2 {
+		t.Errorf("unexpected skel result %#v", es[0])
+	}
+}
+
+func TestLoadFileGzip(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/extract.osm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "extract.osm.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	w.Write(data)
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := s.RunQuery(`node["name"~"^Klein"][!"ref"];out;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].ID != 2 {
+		t.Errorf("unexpected result %#v", es)
+	}
+}
+
+func TestStoreAdd(t *testing.T) {
+	s := NewStore()
+	s.Add(&Element{Type: "node", ID: 1, Lat: -33.9, Lon: 18.4, TagMap: map[string]string{"natural": "peak"}})
+	s.Add(&Element{Type: "node", ID: 2, Lat: -33.91, Lon: 18.41})
+	s.Add(&Element{Type: "area", ID: 3, TagMap: map[string]string{"natural": "peak"}})
+	es, err := s.RunQuery(BuildQuery(DefaultQuery, []poi.Attribute{{Key: "natural", Value: "peak"}}, 5000, -33.9, 18.4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].ID != 1 {
+		t.Errorf("unexpected result %#v", es)
+	}
+}
+
+func TestStoreAddReplaces(t *testing.T) {
+	s := NewStore()
+	peak := map[string]string{"natural": "peak"}
+	s.Add(&Element{Type: "node", ID: 1, Lat: -33.9, Lon: 18.4, TagMap: peak, Version: 1})
+	s.Add(&Element{Type: "node", ID: 1, Lat: -33.9, Lon: 18.4, TagMap: peak, Version: 2})
+	s.Add(&Element{Type: "node", ID: 2, Lat: -33.9, Lon: 18.4, TagMap: peak})
+	s.Add(&Element{Type: "node", ID: 2, Lat: -33.9, Lon: 18.4})
+	s.Add(&Element{Type: "way", ID: 3, TagMap: peak, Version: 1})
+	s.Add(&Element{Type: "way", ID: 3, TagMap: peak, Version: 2})
+	es, err := s.RunQuery(BuildQuery(DefaultQuery, []poi.Attribute{{Key: "natural", Value: "peak"}}, 5000, -33.9, 18.4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].ID != 1 || es[0].Version != 2 {
+		t.Errorf("unexpected nodes %#v", es)
+	}
+	es, err = s.RunQuery(`way["natural"="peak"];out meta;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].ID != 3 || es[0].Version != 2 {
+		t.Errorf("unexpected ways %#v", es)
+	}
+}
+
+func TestLoadFileRejectsPBF(t *testing.T) {
+	if _, err := LoadFile("testdata/extract.osm.pbf"); err == nil {
+		t.Error("expected error loading PBF extract")
+	}
+}
+
+func formatID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
@@ -1,27 +1,17 @@
 package overpass
 
 import (
-	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
-	"time"
 
 	"github.com/godfried/osmimport/poi"
 )
 
-var endpoints = []string{
-	"https://lz4.overpass-api.de/api/interpreter",
-	"https://z.overpass-api.de/api/interpreter",
-	"https://overpass.kumi.systems/api/interpreter",
-}
-
 type Result struct {
 	Elements []*Element
 }
@@ -38,6 +28,32 @@ type Element struct {
 	UID       uint64            `json:"uid"`
 	TagMap    map[string]string `json:"tags"`
 	Nodes     []uint64          `json:"nodes"`
+	Members   []Member          `json:"members"`
+	Center    *Center           `json:"center"`
+}
+
+// TypeOrder ranks the element types node, way and relation in that order, so that elements sorted by it come after
+// their members. Other types rank with nodes.
+func TypeOrder(tipe string) int {
+	switch tipe {
+	case "way":
+		return 1
+	case "relation":
+		return 2
+	}
+	return 0
+}
+
+// Center is the centre of a way or relation, returned by Overpass for out center queries.
+type Center struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type Member struct {
+	Type string `json:"type" xml:"type,attr"`
+	Ref  uint64 `json:"ref" xml:"ref,attr"`
+	Role string `json:"role" xml:"role,attr"`
 }
 
 const elementXML = `
@@ -56,6 +72,9 @@ func (e Element) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 		{Name: xml.Name{Local: "id"}, Value: strconv.FormatUint(e.ID, 10)},
 		{Name: xml.Name{Local: "version"}, Value: strconv.FormatUint(uint64(e.Version), 10)},
 	}
+	if e.Changeset != 0 {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "changeset"}, Value: strconv.FormatUint(e.Changeset, 10)})
+	}
 	if e.Type == "node" {
 		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "lat"}, Value: strconv.FormatFloat(e.Lat, 'f', -1, 64)})
 		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "lon"}, Value: strconv.FormatFloat(e.Lon, 'f', -1, 64)})
@@ -77,8 +96,28 @@ func (e Element) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 			return err
 		}
 	}
-	for k, v := range e.TagMap {
-		err := enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "tag"}, Attr: []xml.Attr{{Name: xml.Name{Local: "k"}, Value: k}, {Name: xml.Name{Local: "v"}, Value: v}}})
+	for _, m := range e.Members {
+		err := enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "member"}, Attr: []xml.Attr{
+			{Name: xml.Name{Local: "type"}, Value: m.Type},
+			{Name: xml.Name{Local: "ref"}, Value: strconv.FormatUint(m.Ref, 10)},
+			{Name: xml.Name{Local: "role"}, Value: m.Role},
+		}})
+		if err != nil {
+			return err
+		}
+		err = enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "member"}})
+		if err != nil {
+			return err
+		}
+	}
+	keys := make([]string, 0, len(e.TagMap))
+	for k := range e.TagMap {
+		keys = append(keys, k)
+	}
+	// Sorted so that the same element is always written the same way.
+	sort.Strings(keys)
+	for _, k := range keys {
+		err := enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "tag"}, Attr: []xml.Attr{{Name: xml.Name{Local: "k"}, Value: k}, {Name: xml.Name{Local: "v"}, Value: e.TagMap[k]}}})
 		if err != nil {
 			return err
 		}
@@ -109,10 +148,16 @@ func (e *Element) Tags() map[string]string {
 }
 
 func (e *Element) Latitude() float64 {
+	if e.Center != nil {
+		return e.Center.Lat
+	}
 	return e.Lat
 }
 
 func (e *Element) Longitude() float64 {
+	if e.Center != nil {
+		return e.Center.Lon
+	}
 	return e.Lon
 }
 
@@ -120,51 +165,6 @@ func (e *Element) AddTag(key, value string) {
 	e.TagMap[key] = value
 }
 
-func runQuery(query string) (*http.Response, error) {
-	log.Printf("running query %s", query)
-	var resp *http.Response
-	var err error
-	vals := url.Values{"data": []string{query}}
-	c := &http.Client{Timeout: 180 * time.Second}
-	retries := 50
-	for i := 0; i < retries; i++ {
-		for _, endpoint := range endpoints {
-			resp, err = c.PostForm(endpoint, vals)
-			if err != nil {
-				log.Printf("Could not load elements: %s", err)
-				continue
-			}
-			if resp.StatusCode != 200 {
-				resp.Body.Close()
-				err = fmt.Errorf("bad status code: %s", resp.Status)
-				continue
-			}
-			return resp, nil
-		}
-		time.Sleep(10 * time.Second)
-	}
-	return nil, err
-}
-
-func loadElements(query string) ([]*Element, error) {
-	resp, err := runQuery(query)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("results: %s", body)
-	result := new(Result)
-	err = json.Unmarshal(body, result)
-	if err != nil {
-		return nil, err
-	}
-	return result.Elements, nil
-}
-
 type NodeLoader func(poi OSMPOI, dist float64) (*Element, error)
 
 type OSMPOI interface {
@@ -177,7 +177,7 @@ func LoadNearestElement(p OSMPOI, dist float64) (*Element, error) {
 	if err != nil {
 		return nil, err
 	}
-	nearest := poi.SelectNearest(nodes, p, dist)
+	nearest := poi.SelectNearest(poi.IndexPOIs(nodes), p, dist)
 	if nearest == nil {
 		return nil, nil
 	}
@@ -207,8 +207,20 @@ func HasMatches(p OSMPOI, dist float64) bool {
 	return m != nil
 }
 
+// Backend answers Overpass QL queries, either remotely or from a local Store.
+type Backend interface {
+	RunQuery(query string) ([]*Element, error)
+}
+
+var backend Backend = DefaultClient
+
+// UseBackend replaces the public Overpass endpoints as the backend for all queries.
+func UseBackend(b Backend) {
+	backend = b
+}
+
 func RunQuery(query string) ([]*Element, error) {
-	return loadElements(query)
+	return backend.RunQuery(query)
 }
 
 const DefaultQuery = `
@@ -218,12 +230,12 @@ const DefaultQuery = `
 {{- $latitude := .Latitude -}}
 {{- $longitude := .Longitude -}}
 {{range .Filters}}
-	node["{{.Key}}"="{{.Value}}"](around:{{$radius}},{{$latitude}},{{$longitude}});
-	way["{{.Key}}"="{{.Value}}"](around:{{$radius}},{{$latitude}},{{$longitude}});
-	relation["{{.Key}}"="{{.Value}}"](around:{{$radius}},{{$latitude}},{{$longitude}});
+	node["{{.Key}}"{{if .Value}}="{{.Value}}"{{end}}](around:{{$radius}},{{$latitude}},{{$longitude}});
+	way["{{.Key}}"{{if .Value}}="{{.Value}}"{{end}}](around:{{$radius}},{{$latitude}},{{$longitude}});
+	relation["{{.Key}}"{{if .Value}}="{{.Value}}"{{end}}](around:{{$radius}},{{$latitude}},{{$longitude}});
 {{- end -}}
 );
-out meta;
+out center meta;
 `
 
 const SAGNSQuery = `
@@ -236,7 +248,7 @@ const SAGNSQuery = `
 	way["sagns_id"](around:{{$radius}},{{$latitude}},{{$longitude}});
 	relation["sagns_id"](around:{{$radius}},{{$latitude}},{{$longitude}});
 );
-out body;
+out body center;
 >;
 out skel qt;`
 
@@ -273,7 +285,7 @@ func BuildQuery(query string, filters []poi.Attribute, radius, lat, lon float64)
 
 func loadMatches(p OSMPOI, dist float64) ([]poi.POI, error) {
 	q := buildQuery(p, dist)
-	es, err := loadElements(q)
+	es, err := RunQuery(q)
 	if err != nil {
 		return nil, err
 	}
@@ -283,3 +295,11 @@ func loadMatches(p OSMPOI, dist float64) ([]poi.POI, error) {
 	}
 	return pois, nil
 }
+
+// Merge returns a copy of e with the tags of p merged in according to policy, along with the per key diff.
+func (e *Element) Merge(p poi.POI, policy poi.MergePolicy) (*Element, []poi.TagDiff) {
+	merged := *e
+	var diffs []poi.TagDiff
+	merged.TagMap, diffs = poi.MergeTags(e.TagMap, p.Tags(), policy)
+	return &merged, diffs
+}
@@ -0,0 +1,125 @@
+package overpass
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+func TestDecodeElements(t *testing.T) {
+	es, err := decodeElements(readFixture(t, "result.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 3 {
+		t.Fatalf("got %d elements, want 3", len(es))
+	}
+	want := &Element{
+		Type:      "node",
+		ID:        1001,
+		Lat:       -33.9626,
+		Lon:       18.4039,
+		Timestamp: "2019-05-01T12:00:00Z",
+		Version:   3,
+		Changeset: 70000001,
+		User:      "mapper",
+		UID:       42,
+		TagMap:    map[string]string{"ele": "1085", "name": "Table Mountain", "natural": "peak", "sagns_id": "12345"},
+	}
+	if !reflect.DeepEqual(es[0], want) {
+		t.Errorf("node = %#v, want %#v", es[0], want)
+	}
+	way := es[1]
+	if !reflect.DeepEqual(way.Nodes, []uint64{3001, 3002, 3003}) {
+		t.Errorf("way nodes = %v", way.Nodes)
+	}
+	if way.Latitude() != -33.95 || way.Longitude() != 18.42 {
+		t.Errorf("way centre = %f,%f, want -33.95,18.42", way.Latitude(), way.Longitude())
+	}
+	if !reflect.DeepEqual(es[2].Members, []Member{{Type: "way", Ref: 2001, Role: "outer"}}) {
+		t.Errorf("relation members = %v", es[2].Members)
+	}
+	if _, err := decodeElements([]byte("<html>rate limited</html>")); err == nil {
+		t.Error("expected error decoding non-JSON response")
+	}
+}
+
+func TestElementNames(t *testing.T) {
+	e := &Element{TagMap: map[string]string{"name": "Tafelberg", "name:en": "Table Mountain", "natural": "peak"}}
+	names := e.Names()
+	if len(names) != 2 {
+		t.Fatalf("Names() = %v, want 2 names", names)
+	}
+	for _, n := range names {
+		if e.TagMap[string(n.Key)] != n.Value {
+			t.Errorf("name %s=%s does not match tags", n.Key, n.Value)
+		}
+	}
+}
+
+func TestElementMarshalXML(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Element
+		want string
+	}{
+		{
+			"node",
+			Element{Type: "node", ID: 1, Version: 2, Lat: -33.5, Lon: 20.25, TagMap: map[string]string{"natural": "peak"}},
+			`<node id="1" version="2" lat="-33.5" lon="20.25"><tag k="natural" v="peak"></tag></node>`,
+		},
+		{
+			"way with changeset",
+			Element{Type: "way", ID: 3, Version: 1, Changeset: 9, Nodes: []uint64{1, 2}},
+			`<way id="3" version="1" changeset="9"><nd ref="1"></nd><nd ref="2"></nd></way>`,
+		},
+		{
+			"relation",
+			Element{Type: "relation", ID: 4, Version: 1, Members: []Member{{Type: "way", Ref: 3, Role: "outer"}}},
+			`<relation id="4" version="1"><member type="way" ref="3" role="outer"></member></relation>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := xml.Marshal(tt.e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	q := BuildQuery(DefaultQuery, []poi.Attribute{{Key: "man_made", Value: "survey_point"}, {Key: "sagns_id"}}, 20000, -33.4, 20)
+	for _, want := range []string{
+		`node["man_made"="survey_point"](around:20000,-33.4,20);`,
+		`relation["man_made"="survey_point"](around:20000,-33.4,20);`,
+		`way["sagns_id"](around:20000,-33.4,20);`,
+		`out center meta;`,
+	} {
+		if !strings.Contains(q, want) {
+			t.Errorf("query %s does not contain %s", q, want)
+		}
+	}
+}
+
+func TestElementMerge(t *testing.T) {
+	e := &Element{Type: "node", ID: 1, Version: 2, TagMap: map[string]string{"natural": "peak", "name": "Old"}}
+	p := &Element{TagMap: map[string]string{"name": "New", "ele": "100"}}
+	merged, diffs := e.Merge(p, poi.DefaultMergePolicy)
+	if merged.TagMap["ele"] != "100" || merged.TagMap["name"] != "Old" || merged.Version != 2 {
+		t.Errorf("merged = %#v", merged)
+	}
+	if _, ok := e.TagMap["ele"]; ok {
+		t.Error("original element modified")
+	}
+	if !poi.Modified(diffs) {
+		t.Errorf("Modified(%v) = false", diffs)
+	}
+}
@@ -0,0 +1,637 @@
+package overpass
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+type statement interface {
+	isStatement()
+}
+
+type queryStatement struct {
+	types   []string
+	filters []tagFilter
+	around  *poi.CircleBox
+	bbox    *poi.BBox
+	cond    condition
+}
+
+type unionStatement struct {
+	queries []*queryStatement
+}
+
+type recurseStatement struct{}
+
+type outStatement struct {
+	mode   string
+	center bool
+}
+
+func (*queryStatement) isStatement()   {}
+func (*unionStatement) isStatement()   {}
+func (*recurseStatement) isStatement() {}
+func (*outStatement) isStatement()     {}
+
+type tagFilter struct {
+	key    string
+	op     string
+	value  string
+	regexp *regexp.Regexp
+}
+
+func (f tagFilter) matches(tags map[string]string) bool {
+	v, ok := tags[f.key]
+	switch f.op {
+	case "":
+		return ok
+	case "!":
+		return !ok
+	case "=":
+		return ok && v == f.value
+	case "!=":
+		return !ok || v != f.value
+	case "~":
+		return ok && f.regexp.MatchString(v)
+	case "!~":
+		return !ok || !f.regexp.MatchString(v)
+	}
+	return false
+}
+
+// positive reports whether the filter can only match elements with tags.
+func (f tagFilter) positive() bool {
+	return f.op == "" || f.op == "=" || f.op == "~"
+}
+
+// condition is a disjunction of conjunctions of comparisons, as written in an (if:...) filter.
+type condition [][]comparison
+
+type comparison struct {
+	left, op, right operand
+}
+
+type operand struct {
+	tag     string
+	literal string
+}
+
+func (o operand) value(tags map[string]string) string {
+	if o.tag != "" {
+		return tags[o.tag]
+	}
+	return o.literal
+}
+
+func (c comparison) holds(tags map[string]string) bool {
+	l, r := c.left.value(tags), c.right.value(tags)
+	lf, lerr := strconv.ParseFloat(l, 64)
+	rf, rerr := strconv.ParseFloat(r, 64)
+	if lerr == nil && rerr == nil {
+		switch c.op.literal {
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		case ">":
+			return lf > rf
+		case ">=":
+			return lf >= rf
+		case "==":
+			return lf == rf
+		case "!=":
+			return lf != rf
+		}
+		return false
+	}
+	switch c.op.literal {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	}
+	return false
+}
+
+func (c condition) holds(tags map[string]string) bool {
+	if len(c) == 0 {
+		return true
+	}
+	for _, and := range c {
+		ok := true
+		for _, cmp := range and {
+			if !cmp.holds(tags) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+type parser struct {
+	query string
+	pos   int
+}
+
+func parseQuery(query string) ([]statement, error) {
+	p := &parser{query: query}
+	stmts := make([]statement, 0, 4)
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return stmts, nil
+		}
+		switch c := p.peek(); {
+		case c == ';':
+			p.pos++
+		case c == '[':
+			p.skipSettings()
+		case c == '(':
+			p.pos++
+			u, err := p.parseUnion()
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, u)
+		case c == '>':
+			p.pos++
+			if err := p.expect(';'); err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, &recurseStatement{})
+		case strings.HasPrefix(p.query[p.pos:], "._"):
+			p.pos += 2
+		default:
+			ident := p.ident()
+			switch ident {
+			case "out":
+				stmts = append(stmts, p.outStatement())
+			case "node", "way", "relation", "nwr":
+				q, err := p.parseQueryStatement(ident)
+				if err != nil {
+					return nil, err
+				}
+				stmts = append(stmts, q)
+			default:
+				return nil, p.errorf("unsupported statement %q", ident)
+			}
+		}
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("cannot parse query at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.query)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.query[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c != '_' && c != ':' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.query[start:p.pos]
+}
+
+func (p *parser) skipSettings() {
+	for !p.eof() && p.peek() != ';' {
+		p.pos++
+	}
+}
+
+func (p *parser) outStatement() *outStatement {
+	out := &outStatement{mode: "body"}
+	for {
+		p.skipSpace()
+		if p.eof() || p.peek() == ';' {
+			return out
+		}
+		switch m := p.ident(); m {
+		case "ids", "skel", "body", "tags", "meta":
+			out.mode = m
+		case "center":
+			out.center = true
+		case "":
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseUnion() (*unionStatement, error) {
+	u := &unionStatement{}
+	for {
+		p.skipSpace()
+		switch {
+		case p.eof():
+			return nil, p.errorf("unterminated union")
+		case p.peek() == ')':
+			p.pos++
+			return u, nil
+		case p.peek() == ';':
+			p.pos++
+			continue
+		}
+		ident := p.ident()
+		switch ident {
+		case "node", "way", "relation", "nwr":
+		default:
+			return nil, p.errorf("unsupported union member %q", ident)
+		}
+		q, err := p.parseQueryStatement(ident)
+		if err != nil {
+			return nil, err
+		}
+		u.queries = append(u.queries, q)
+	}
+}
+
+func (p *parser) parseQueryStatement(tipe string) (*queryStatement, error) {
+	q := &queryStatement{types: []string{tipe}}
+	if tipe == "nwr" {
+		q.types = []string{"node", "way", "relation"}
+	}
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case ';':
+			p.pos++
+			return q, nil
+		case '[':
+			p.pos++
+			f, err := p.parseTagFilter()
+			if err != nil {
+				return nil, err
+			}
+			q.filters = append(q.filters, f)
+		case '(':
+			p.pos++
+			err := p.parseSpatialFilter(q)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, p.errorf("unexpected %q in query", p.peek())
+		}
+	}
+}
+
+func (p *parser) parseString() (string, error) {
+	p.skipSpace()
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		start := p.pos
+		for !p.eof() && !strings.ContainsRune("=!~]", rune(p.peek())) {
+			p.pos++
+		}
+		return strings.TrimSpace(p.query[start:p.pos]), nil
+	}
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '\\':
+			b.WriteByte(p.peek())
+			p.pos++
+		case quote:
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *parser) parseTagFilter() (tagFilter, error) {
+	f := tagFilter{}
+	p.skipSpace()
+	negate := false
+	if p.peek() == '!' {
+		negate = true
+		p.pos++
+	}
+	key, err := p.parseString()
+	if err != nil {
+		return f, err
+	}
+	f.key = key
+	p.skipSpace()
+	switch {
+	case p.peek() == ']':
+		p.pos++
+		if negate {
+			f.op = "!"
+		}
+		return f, nil
+	case strings.HasPrefix(p.query[p.pos:], "!="), strings.HasPrefix(p.query[p.pos:], "!~"):
+		f.op = p.query[p.pos : p.pos+2]
+		p.pos += 2
+	case p.peek() == '=', p.peek() == '~':
+		f.op = p.query[p.pos : p.pos+1]
+		p.pos++
+	default:
+		return f, p.errorf("unsupported tag filter")
+	}
+	f.value, err = p.parseString()
+	if err != nil {
+		return f, err
+	}
+	if f.op == "~" || f.op == "!~" {
+		f.regexp, err = regexp.Compile(f.value)
+		if err != nil {
+			return f, p.errorf("invalid regular expression %q: %s", f.value, err)
+		}
+	}
+	return f, p.expect(']')
+}
+
+func (p *parser) parseSpatialFilter(q *queryStatement) error {
+	end := strings.IndexByte(p.query[p.pos:], ')')
+	if strings.HasPrefix(strings.TrimSpace(p.query[p.pos:]), "if:") {
+		end = p.matchingParen()
+	}
+	if end < 0 {
+		return p.errorf("unterminated filter")
+	}
+	content := strings.TrimSpace(p.query[p.pos : p.pos+end])
+	p.pos += end + 1
+	switch {
+	case strings.HasPrefix(content, "if:"):
+		cond, err := parseCondition(strings.TrimPrefix(content, "if:"))
+		if err != nil {
+			return p.errorf("%s", err)
+		}
+		q.cond = append(q.cond, cond...)
+		return nil
+	case strings.HasPrefix(content, "around:"):
+		vals, err := parseFloats(strings.TrimPrefix(content, "around:"))
+		if err != nil || len(vals) != 3 {
+			return p.errorf("unsupported around filter %q", content)
+		}
+		q.around = &poi.CircleBox{RadiusKM: vals[0] / 1000, Lat: vals[1], Lon: vals[2]}
+		return nil
+	default:
+		vals, err := parseFloats(content)
+		if err != nil || len(vals) != 4 {
+			return p.errorf("unsupported filter %q", content)
+		}
+		q.bbox = &poi.BBox{MinLat: vals[0], MinLon: vals[1], MaxLat: vals[2], MaxLon: vals[3]}
+		return nil
+	}
+}
+
+// matchingParen returns the offset of the parenthesis closing the filter starting at the parser's position.
+func (p *parser) matchingParen() int {
+	depth := 0
+	for i := p.pos; i < len(p.query); i++ {
+		switch p.query[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i - p.pos
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func parseFloats(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	vals := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+	return vals, nil
+}
+
+var conditionToken = regexp.MustCompile(`\s*(t\[\s*"([^"]*)"\s*\]|"([^"]*)"|-?[0-9.]+|&&|\|\||<=|>=|==|!=|<|>)`)
+
+func parseCondition(expr string) (condition, error) {
+	var cond condition
+	var and []comparison
+	var cmp []operand
+	rest := strings.TrimSpace(expr)
+	for rest != "" {
+		m := conditionToken.FindStringSubmatchIndex(rest)
+		if m == nil || m[0] != 0 {
+			return nil, fmt.Errorf("unsupported condition %q", expr)
+		}
+		tok := rest[m[2]:m[3]]
+		switch {
+		case tok == "&&", tok == "||":
+			if len(cmp) != 3 {
+				return nil, fmt.Errorf("unsupported condition %q", expr)
+			}
+			and = append(and, comparison{left: cmp[0], op: cmp[1], right: cmp[2]})
+			cmp = nil
+			if tok == "||" {
+				cond = append(cond, and)
+				and = nil
+			}
+		case m[4] >= 0:
+			cmp = append(cmp, operand{tag: rest[m[4]:m[5]]})
+		case m[6] >= 0:
+			cmp = append(cmp, operand{literal: rest[m[6]:m[7]]})
+		default:
+			cmp = append(cmp, operand{literal: tok})
+		}
+		rest = strings.TrimSpace(rest[m[1]:])
+	}
+	if len(cmp) != 3 {
+		return nil, fmt.Errorf("unsupported condition %q", expr)
+	}
+	and = append(and, comparison{left: cmp[0], op: cmp[1], right: cmp[2]})
+	return append(cond, and), nil
+}
+
+func (s *Store) run(stmts []statement) []*Element {
+	var set, results []*Element
+	for _, stmt := range stmts {
+		switch st := stmt.(type) {
+		case *queryStatement:
+			set = s.query(st)
+		case *unionStatement:
+			set = s.union(st)
+		case *recurseStatement:
+			set = s.recurseDown(set)
+		case *outStatement:
+			results = append(results, s.output(set, st)...)
+		}
+	}
+	return results
+}
+
+func (s *Store) union(u *unionStatement) []*Element {
+	seen := make(map[string]map[uint64]struct{}, 3)
+	var set []*Element
+	for _, q := range u.queries {
+		for _, e := range s.query(q) {
+			if seen[e.Type] == nil {
+				seen[e.Type] = make(map[uint64]struct{})
+			}
+			if _, ok := seen[e.Type][e.ID]; ok {
+				continue
+			}
+			seen[e.Type][e.ID] = struct{}{}
+			set = append(set, e)
+		}
+	}
+	return set
+}
+
+func (s *Store) query(q *queryStatement) []*Element {
+	var set []*Element
+	for _, tipe := range q.types {
+		for _, e := range s.candidates(q, tipe) {
+			if s.matches(q, e) {
+				set = append(set, e)
+			}
+		}
+	}
+	return set
+}
+
+func (s *Store) candidates(q *queryStatement, tipe string) []*Element {
+	positive := false
+	for _, f := range q.filters {
+		if f.positive() {
+			positive = true
+		}
+	}
+	if positive && tipe == "node" && q.around != nil {
+		found := s.index.Within(q.around.Lat, q.around.Lon, q.around.Radius())
+		es := make([]*Element, 0, len(found))
+		for _, f := range found {
+			es = append(es, f.(*Element))
+		}
+		return es
+	}
+	if positive {
+		return s.tagged[tipe]
+	}
+	all := s.elements(tipe)
+	es := make([]*Element, 0, len(all))
+	for _, e := range all {
+		es = append(es, e)
+	}
+	return es
+}
+
+func (s *Store) matches(q *queryStatement, e *Element) bool {
+	for _, f := range q.filters {
+		if !f.matches(e.TagMap) {
+			return false
+		}
+	}
+	if !q.cond.holds(e.TagMap) {
+		return false
+	}
+	if q.around == nil && q.bbox == nil {
+		return true
+	}
+	for _, n := range s.geometry(e, make(map[uint64]struct{})) {
+		if (q.around == nil || q.around.Contains(n)) && (q.bbox == nil || q.bbox.Contains(n)) {
+			return true
+		}
+	}
+	return false
+}
+
+// output copies set with the detail level of an Overpass out statement, ordered by type and id.
+func (s *Store) output(set []*Element, st *outStatement) []*Element {
+	mode := st.mode
+	out := make([]*Element, 0, len(set))
+	for _, e := range set {
+		c := &Element{Type: e.Type, ID: e.ID}
+		if mode != "ids" && mode != "tags" {
+			c.Lat, c.Lon, c.Nodes, c.Members = e.Lat, e.Lon, e.Nodes, e.Members
+		}
+		if mode == "body" || mode == "tags" || mode == "meta" {
+			c.TagMap = make(map[string]string, len(e.TagMap))
+			for k, v := range e.TagMap {
+				c.TagMap[k] = v
+			}
+		}
+		if mode == "meta" {
+			c.Timestamp, c.Version, c.Changeset, c.User, c.UID = e.Timestamp, e.Version, e.Changeset, e.User, e.UID
+		}
+		if st.center && e.Type != "node" {
+			c.Center = s.center(e)
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return TypeOrder(out[i].Type) < TypeOrder(out[j].Type)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// center returns the centre of the bounding box of e's geometry.
+func (s *Store) center(e *Element) *Center {
+	nodes := s.geometry(e, make(map[uint64]struct{}))
+	if len(nodes) == 0 {
+		return nil
+	}
+	b := poi.BBox{MinLat: nodes[0].Lat, MaxLat: nodes[0].Lat, MinLon: nodes[0].Lon, MaxLon: nodes[0].Lon}
+	for _, n := range nodes[1:] {
+		b.MinLat, b.MaxLat = math.Min(b.MinLat, n.Lat), math.Max(b.MaxLat, n.Lat)
+		b.MinLon, b.MaxLon = math.Min(b.MinLon, n.Lon), math.Max(b.MaxLon, n.Lon)
+	}
+	return &Center{Lat: (b.MinLat + b.MaxLat) / 2, Lon: (b.MinLon + b.MaxLon) / 2}
+}
@@ -0,0 +1,162 @@
+package osm
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/godfried/osmimport/poi"
+)
+
+// Writer streams an OSM document, writing each node as it is added instead of holding them all in memory. The bounds
+// are only known once every node has been written, so they follow the nodes at the end of the document.
+type Writer struct {
+	buf    *bufio.Writer
+	enc    *xml.Encoder
+	bounds *Bounds
+	nextID int
+	closer io.Closer
+	closed bool
+}
+
+// NewWriter starts an OSM document on w, buffering what is written to it until Close.
+func NewWriter(w io.Writer) (*Writer, error) {
+	buf := bufio.NewWriter(w)
+	_, err := buf.WriteString(xml.Header)
+	if err != nil {
+		return nil, err
+	}
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "  ")
+	err = enc.EncodeToken(xml.StartElement{
+		Name: xml.Name{Local: "osm"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "version"}, Value: "0.6"},
+			{Name: xml.Name{Local: "generator"}, Value: "JOSM"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{buf: buf, enc: enc, bounds: NewBounds(), nextID: -1}, nil
+}
+
+// Create starts an OSM document in outFile, compressed with gzip or bzip2 if its name ends in .gz or .bz2.
+func Create(outFile string) (*Writer, error) {
+	f, err := createFile(outFile)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// WritePOI writes p as a new node with the next placeholder negative ID.
+func (w *Writer) WritePOI(p poi.POI) error {
+	err := w.WriteNode(NewNode(p, w.nextID))
+	if err != nil {
+		return err
+	}
+	w.nextID--
+	return nil
+}
+
+// WriteNode writes n and expands the bounds to include it.
+func (w *Writer) WriteNode(n *Node) error {
+	if w.closed {
+		return fmt.Errorf("write to closed OSM writer")
+	}
+	err := w.enc.Encode(n)
+	if err != nil {
+		return err
+	}
+	w.bounds.expand(n.Lat, n.Lon)
+	return nil
+}
+
+// Bounds returns the bounds of the nodes written so far.
+func (w *Writer) Bounds() Bounds {
+	return *w.bounds
+}
+
+// Close ends the document with its bounds, flushes it and closes the file written to, if the Writer was made by Create.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.enc.EncodeElement(w.bounds, xml.StartElement{Name: xml.Name{Local: "bounds"}})
+	if err == nil {
+		err = w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "osm"}})
+	}
+	if err == nil {
+		err = w.enc.Flush()
+	}
+	if err == nil {
+		err = w.buf.Flush()
+	}
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// createFile creates outFile, writable only by its owner, compressing what is written to it with gzip or bzip2 if its
+// name ends in .gz or .bz2.
+func createFile(outFile string) (io.WriteCloser, error) {
+	f, err := os.OpenFile(outFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasSuffix(outFile, ".gz"):
+		return &compressedFile{WriteCloser: gzip.NewWriter(f), f: f}, nil
+	case strings.HasSuffix(outFile, ".bz2"):
+		bw, err := bzip2.NewWriter(f, nil)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &compressedFile{WriteCloser: bw, f: f}, nil
+	}
+	return f, nil
+}
+
+// compressedFile closes its compressor, flushing the compressed stream, before the file underneath it.
+type compressedFile struct {
+	io.WriteCloser
+	f *os.File
+}
+
+func (c *compressedFile) Close() error {
+	err := c.WriteCloser.Close()
+	if cerr := c.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeFile writes data to outFile as createFile does.
+func writeFile(outFile string, data []byte) error {
+	f, err := createFile(outFile)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package osm
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range testPOIs {
+		if err := w.WritePOI(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b := w.Bounds(); b.MinLat != -33.9626 || b.MaxLat != -33.9355 || b.MinLon != 18.3892 || b.MaxLon != 18.4039 {
+		t.Errorf("Bounds() = %+v", b)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePOI(testPOIs[0]); err == nil {
+		t.Error("WritePOI() after Close() should fail")
+	}
+	got := new(OSM)
+	if err := xml.Unmarshal(buf.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Node) != 2 || got.Node[0].ID != -1 || got.Node[1].ID != -2 {
+		t.Errorf("nodes = %v, want -1 and -2", got.Node)
+	}
+	if got.Bounds == nil || got.Bounds.MaxLon != 18.4039 {
+		t.Errorf("bounds = %+v, want the trailer", got.Bounds)
+	}
+}
+
+func TestGenerateXMLFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		decompress func(io.Reader) (io.Reader, error)
+	}{
+		{"out.osm", func(r io.Reader) (io.Reader, error) { return r, nil }},
+		{"out.osm.gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"out.osm.bz2", func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), tt.name)
+			if err := GenerateXML(testPOIs, out); err != nil {
+				t.Fatal(err)
+			}
+			fi, err := os.Stat(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := fi.Mode().Perm(); perm&0133 != 0 {
+				t.Errorf("file mode = %s, want no execute or group and other write permissions", perm)
+			}
+			f, err := os.Open(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			r, err := tt.decompress(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := new(OSM)
+			if err := xml.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Node) != len(testPOIs) {
+				t.Errorf("got %d nodes, want %d", len(got.Node), len(testPOIs))
+			}
+		})
+	}
+}
@@ -2,22 +2,21 @@ package osm
 
 import (
 	"encoding/xml"
+	"sort"
 
 	"math"
 
-	"io/ioutil"
-	"os"
-
 	"github.com/godfried/osmimport/poi"
 )
 
 type Node struct {
-	XMLName xml.Name `xml:"node"`
-	Lat     float64  `xml:"lat,attr"`
-	Lon     float64  `xml:"lon,attr"`
-	ID      int      `xml:"id,attr"`
-	Visible bool     `xml:"visible,attr"`
-	Tag     []Tag
+	XMLName   xml.Name `xml:"node"`
+	Lat       float64  `xml:"lat,attr"`
+	Lon       float64  `xml:"lon,attr"`
+	ID        int      `xml:"id,attr"`
+	Visible   bool     `xml:"visible,attr"`
+	Changeset uint64   `xml:"changeset,attr,omitempty"`
+	Tag       []Tag    `xml:"tag"`
 }
 
 type Bounds struct {
@@ -39,17 +38,21 @@ func NewBounds() *Bounds {
 }
 
 func (b *Bounds) Expand(p poi.POI) {
-	if b.MinLon > p.Longitude() {
-		b.MinLon = p.Longitude()
+	b.expand(p.Latitude(), p.Longitude())
+}
+
+func (b *Bounds) expand(lat, lon float64) {
+	if b.MinLon > lon {
+		b.MinLon = lon
 	}
-	if b.MinLat > p.Latitude() {
-		b.MinLat = p.Latitude()
+	if b.MinLat > lat {
+		b.MinLat = lat
 	}
-	if b.MaxLon < p.Longitude() {
-		b.MaxLon = p.Longitude()
+	if b.MaxLon < lon {
+		b.MaxLon = lon
 	}
-	if b.MaxLat < p.Latitude() {
-		b.MaxLat = p.Latitude()
+	if b.MaxLat < lat {
+		b.MaxLat = lat
 	}
 }
 
@@ -57,8 +60,8 @@ type OSM struct {
 	XMLName   xml.Name `xml:"osm"`
 	Version   string   `xml:"version,attr"`
 	Generator string   `xml:"generator,attr"`
-	Node      []*Node
-	Bounds    *Bounds `xml:"bounds"`
+	Node      []*Node  `xml:"node"`
+	Bounds    *Bounds  `xml:"bounds"`
 }
 
 func NewOSM(size int) *OSM {
@@ -70,12 +73,14 @@ func NewOSM(size int) *OSM {
 	}
 }
 
+// NewNode returns p as a node with id, its tags sorted by key.
 func NewNode(p poi.POI, id int) *Node {
 	poiTags := p.Tags()
 	tags := make([]Tag, 0, len(poiTags))
 	for k, v := range poiTags {
 		tags = append(tags, Tag{Key: k, Value: v})
 	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
 	return &Node{Lat: p.Latitude(), Lon: p.Longitude(), Tag: tags, Visible: true, ID: id}
 }
 
@@ -85,17 +90,20 @@ type Tag struct {
 	Value   string   `xml:"v,attr"`
 }
 
+// GenerateXML writes pois to outFile as new nodes, sorted by SortNew, compressed if its name ends in .gz or .bz2.
 func GenerateXML(pois []poi.POI, outFile string) error {
-	o := NewOSM(len(pois))
-	for i, p := range pois {
-		o.Node = append(o.Node, NewNode(p, -1*(i+1)))
-		o.Bounds.Expand(p)
-	}
-	data, err := xml.MarshalIndent(o, "", "  ")
+	w, err := Create(outFile)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(outFile, data, os.ModePerm)
+	for _, p := range SortNew(pois) {
+		err = w.WritePOI(p)
+		if err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
 }
 
 func GenerateUpdateXML(pois []poi.POI, outFile string) error {
@@ -104,7 +112,7 @@ func GenerateUpdateXML(pois []poi.POI, outFile string) error {
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(outFile, data, os.ModePerm)
+	return writeFile(outFile, data)
 }
 
 type OSMUpdate struct {
@@ -115,11 +123,13 @@ type OSMUpdate struct {
 	Bounds    *Bounds `xml:"bounds"`
 }
 
+// NewOSMUpdate returns an update of pois, with the OSM elements among them sorted by type and ID ahead of the rest.
 func NewOSMUpdate(pois []poi.POI) *OSMUpdate {
 	b := NewBounds()
 	for _, p := range pois {
 		b.Expand(p)
 	}
+	pois = sortPOIs(pois)
 	return &OSMUpdate{
 		Version:   "0.6",
 		Generator: "JOSM",
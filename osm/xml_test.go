@@ -0,0 +1,102 @@
+package osm
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+type testPOI struct {
+	lat, lon float64
+	tags     map[string]string
+	id       string
+}
+
+func (p testPOI) SourceID() (string, bool) {
+	return p.id, p.id != ""
+}
+
+func (p testPOI) String() string {
+	return p.tags["name"]
+}
+
+func (p testPOI) Latitude() float64 {
+	return p.lat
+}
+
+func (p testPOI) Longitude() float64 {
+	return p.lon
+}
+
+func (p testPOI) Names() []poi.Name {
+	return []poi.Name{{Key: poi.NameKeyDefault, Value: p.tags["name"]}}
+}
+
+func (p testPOI) Tags() map[string]string {
+	return p.tags
+}
+
+func (p testPOI) AddTag(key, value string) {
+	p.tags[key] = value
+}
+
+var testPOIs = []poi.POI{
+	testPOI{lat: -33.9626, lon: 18.4039, tags: map[string]string{"name": "Table Mountain", "natural": "peak", "ele": "1085"}},
+	testPOI{lat: -33.9355, lon: 18.3892, tags: map[string]string{"name": "Lion's Head", "natural": "peak"}},
+}
+
+func tagMap(tags []Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+func TestGenerateXML(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.xml")
+	err := GenerateXML(testPOIs, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(OSM)
+	err = xml.Unmarshal(data, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "0.6" {
+		t.Errorf("version = %s, want 0.6", got.Version)
+	}
+	if len(got.Node) != len(testPOIs) {
+		t.Fatalf("got %d nodes, want %d", len(got.Node), len(testPOIs))
+	}
+	for i, n := range got.Node {
+		p := testPOIs[i]
+		if n.ID != -(i + 1) {
+			t.Errorf("node %d has id %d, want %d", i, n.ID, -(i + 1))
+		}
+		if n.Lat != p.Latitude() || n.Lon != p.Longitude() {
+			t.Errorf("node %d at %f,%f, want %f,%f", i, n.Lat, n.Lon, p.Latitude(), p.Longitude())
+		}
+		tags := tagMap(n.Tag)
+		if len(tags) != len(p.Tags()) {
+			t.Errorf("node %d has tags %v, want %v", i, tags, p.Tags())
+		}
+		for k, v := range p.Tags() {
+			if tags[k] != v {
+				t.Errorf("node %d has %s=%s, want %s", i, k, tags[k], v)
+			}
+		}
+	}
+	want := Bounds{MinLat: -33.9626, MinLon: 18.3892, MaxLat: -33.9355, MaxLon: 18.4039, Origin: "OpenStreetMap server"}
+	if *got.Bounds != want {
+		t.Errorf("bounds = %#v, want %#v", *got.Bounds, want)
+	}
+}
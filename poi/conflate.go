@@ -0,0 +1,295 @@
+package poi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type MatchStatus int
+
+const (
+	// StatusNew means no existing element corresponds to the source POI.
+	StatusNew MatchStatus = iota
+	// StatusExact means an existing element shares the source POI's key or name and position.
+	StatusExact
+	// StatusProbable means an existing element nearby has a similar name.
+	StatusProbable
+	// StatusConflict means existing elements contradict the source POI or are ambiguous.
+	StatusConflict
+)
+
+func (s MatchStatus) String() string {
+	switch s {
+	case StatusNew:
+		return "new"
+	case StatusExact:
+		return "exact"
+	case StatusProbable:
+		return "probable"
+	case StatusConflict:
+		return "conflict"
+	}
+	return fmt.Sprintf("MatchStatus(%d)", int(s))
+}
+
+// Match is the outcome of conflating a single source POI.
+type Match struct {
+	Source     POI
+	Target     POI
+	Status     MatchStatus
+	Confidence float64
+	Distance   float64
+	Reasons    []string
+	// ByKey is set if Target has the same Conflator.Key value as Source, the only kind of match trusted to identify
+	// the same feature without review.
+	ByKey bool
+}
+
+func (m *Match) reason(format string, args ...interface{}) {
+	m.Reasons = append(m.Reasons, fmt.Sprintf(format, args...))
+}
+
+// Conflator matches source POIs against existing OSM elements.
+type Conflator struct {
+	// Key is a tag identifying the same feature in both datasets, e.g. sagns_id or ref.
+	Key string
+	// AltKeys returns further values of Key which may identify s in OSM, such as a beacon number without its area.
+	// They are less reliable than s's own Key tag, so elements matching them are at best probable matches.
+	AltKeys func(s POI) []string
+	// RequireKey makes elements matching by name and position at best probable matches, for sources where only Key
+	// identifies a feature with certainty.
+	RequireKey bool
+	// KeyDistance is the furthest in metres a key match may be from the source POI.
+	KeyDistance float64
+	// ExactDistance is the furthest in metres an element with the same name may be to be an exact match.
+	ExactDistance float64
+	// SearchDistance is the radius in metres searched for probable matches.
+	SearchDistance float64
+	// MinScore is the lowest name and distance score considered a probable match.
+	MinScore float64
+	// MaxEleDiff is the largest elevation difference in metres before a candidate is penalised.
+	MaxEleDiff float64
+}
+
+func NewConflator(key string) *Conflator {
+	return &Conflator{
+		Key:            key,
+		KeyDistance:    1000,
+		ExactDistance:  100,
+		SearchDistance: 2000,
+		MinScore:       0.6,
+		MaxEleDiff:     50,
+	}
+}
+
+// ambiguityMargin is how close the two best candidates' scores must be for a match to be ambiguous.
+const ambiguityMargin = 0.05
+
+// Conflate classifies every source POI against targets, returning matches in the order of source.
+func (c *Conflator) Conflate(source, targets []POI) Report {
+	idx := IndexPOIs(targets)
+	keyed := make(map[string][]POI)
+	if c.Key != "" {
+		for _, t := range targets {
+			if v, ok := t.Tags()[c.Key]; ok {
+				keyed[v] = append(keyed[v], t)
+			}
+		}
+	}
+	report := make(Report, 0, len(source))
+	for _, s := range source {
+		m, ok := c.matchKey(s, keyed)
+		if !ok {
+			m = c.matchNearby(s, idx)
+		}
+		report = append(report, m)
+	}
+	return report
+}
+
+func (c *Conflator) matchKey(s POI, keyed map[string][]POI) (*Match, bool) {
+	if c.Key == "" {
+		return nil, false
+	}
+	v, ok := s.Tags()[c.Key]
+	if !ok || len(keyed[v]) == 0 {
+		return c.matchAltKeys(s, keyed)
+	}
+	ts := keyed[v]
+	m := &Match{Source: s, Target: nearest(s, ts), ByKey: true}
+	m.Distance = distance(s, m.Target)
+	switch {
+	case len(ts) > 1:
+		m.Status = StatusConflict
+		m.Confidence = 0.5
+		m.reason("%d elements have %s=%s", len(ts), c.Key, v)
+	case m.Distance > c.KeyDistance:
+		m.Status = StatusConflict
+		m.Confidence = 0.5
+		m.reason("%s=%s matches but is %.0fm away", c.Key, v, m.Distance)
+	default:
+		m.Status = StatusExact
+		m.Confidence = 1
+		m.reason("%s=%s matches %.0fm away", c.Key, v, m.Distance)
+	}
+	return m, true
+}
+
+// matchAltKeys matches s against the elements tagged with one of its AltKeys within KeyDistance.
+func (c *Conflator) matchAltKeys(s POI, keyed map[string][]POI) (*Match, bool) {
+	if c.AltKeys == nil {
+		return nil, false
+	}
+	var ts []POI
+	var vs []string
+	for _, v := range c.AltKeys(s) {
+		for _, t := range keyed[v] {
+			if distance(s, t) <= c.KeyDistance {
+				ts = append(ts, t)
+				vs = append(vs, v)
+			}
+		}
+	}
+	if len(ts) == 0 {
+		return nil, false
+	}
+	m := &Match{Source: s, Target: nearest(s, ts), Confidence: 0.5}
+	m.Distance = distance(s, m.Target)
+	if len(ts) > 1 {
+		m.Status = StatusConflict
+		m.reason("%d elements nearby have an alternate %s %v", len(ts), c.Key, vs)
+	} else {
+		m.Status = StatusProbable
+		m.reason("alternate %s=%s matches %.0fm away", c.Key, vs[0], m.Distance)
+	}
+	return m, true
+}
+
+func (c *Conflator) matchNearby(s POI, idx *Index) *Match {
+	m := &Match{Source: s, Status: StatusNew}
+	var best, second float64
+	var bestReasons []string
+	for _, t := range idx.Within(s.Latitude(), s.Longitude(), c.SearchDistance) {
+		score, reasons := c.score(s, t)
+		if score > best {
+			second, best, bestReasons = best, score, reasons
+			m.Target = t
+		} else if score > second {
+			second = score
+		}
+	}
+	if m.Target == nil || best < c.MinScore {
+		m.Confidence = 1 - best
+		m.reason("no element within %.0fm scores above %.2f", c.SearchDistance, c.MinScore)
+		m.Target = nil
+		return m
+	}
+	m.Distance = distance(s, m.Target)
+	m.Confidence = best
+	m.Reasons = bestReasons
+	tv, targetKeyed := m.Target.Tags()[c.Key]
+	sv := s.Tags()[c.Key]
+	switch {
+	case c.Key != "" && targetKeyed && tv != sv:
+		m.Status = StatusConflict
+		m.reason("best candidate already has %s=%s", c.Key, tv)
+	case second >= c.MinScore && best-second < ambiguityMargin:
+		m.Status = StatusConflict
+		m.reason("several candidates score within %.2f", ambiguityMargin)
+	case nameScore(s, m.Target) == 1 && m.Distance <= c.ExactDistance && c.RequireKey:
+		m.Status = StatusProbable
+		m.reason("name matches but %s does not", c.Key)
+	case nameScore(s, m.Target) == 1 && m.Distance <= c.ExactDistance:
+		m.Status = StatusExact
+	default:
+		m.Status = StatusProbable
+	}
+	return m
+}
+
+// score rates how likely t is the same feature as s, between 0 and 1, weighing name similarity above proximity.
+func (c *Conflator) score(s, t POI) (float64, []string) {
+	dist := distance(s, t)
+	distScore := 1 - dist/c.SearchDistance
+	reasons := []string{fmt.Sprintf("%.0fm away", dist)}
+	var score float64
+	if len(s.Names()) == 0 || len(t.Names()) == 0 {
+		score = 0.5 * distScore
+		reasons = append(reasons, "no names to compare")
+	} else {
+		ns := nameScore(s, t)
+		score = 0.6*ns + 0.4*distScore
+		reasons = append(reasons, fmt.Sprintf("name similarity %.2f", ns))
+	}
+	se, serr := strconv.ParseFloat(s.Tags()["ele"], 64)
+	te, terr := strconv.ParseFloat(t.Tags()["ele"], 64)
+	if serr == nil && terr == nil && math.Abs(se-te) > c.MaxEleDiff {
+		score /= 2
+		reasons = append(reasons, fmt.Sprintf("elevation differs by %.0fm", math.Abs(se-te)))
+	}
+	return score, reasons
+}
+
+// nameScore is the best similarity between any pair of names of a and b, 1 meaning identical.
+func nameScore(a, b POI) float64 {
+	best := 0.0
+	for _, an := range a.Names() {
+		na := normaliseName(an)
+		for _, bn := range b.Names() {
+			nb := normaliseName(bn)
+			if na == "" || nb == "" {
+				continue
+			}
+			score := 1 - LevenshteinRatio(na, nb)
+			if score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
+
+func distance(a, b POI) float64 {
+	return Distance(a.Latitude(), a.Longitude(), b.Latitude(), b.Longitude())
+}
+
+func nearest(p POI, pois []POI) POI {
+	var n POI
+	minDist := math.MaxFloat64
+	for _, c := range pois {
+		if d := distance(p, c); d < minDist {
+			minDist, n = d, c
+		}
+	}
+	return n
+}
+
+// Report holds a Match for every conflated source POI.
+type Report []*Match
+
+func (r Report) WithStatus(s MatchStatus) Report {
+	matches := make(Report, 0, len(r))
+	for _, m := range r {
+		if m.Status == s {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func (r Report) Summary() map[MatchStatus]int {
+	summary := make(map[MatchStatus]int, 4)
+	for _, m := range r {
+		summary[m.Status]++
+	}
+	return summary
+}
+
+func (r Report) Sources() []POI {
+	pois := make([]POI, 0, len(r))
+	for _, m := range r {
+		pois = append(pois, m.Source)
+	}
+	return pois
+}
@@ -0,0 +1,141 @@
+package poi
+
+import "testing"
+
+func TestConflate(t *testing.T) {
+	table := newTestPOI("Table Mountain", -33.9626, 18.4039, "sagns_id", "1")
+	devils := newTestPOI("Devils Peak", -33.9515, 18.4414)
+	lions := newTestPOI("Lion's Head", -33.9355, 18.3892, "sagns_id", "9")
+	twelve := newTestPOI("Twelve Apostles", -34.0, 18.38, "sagns_id", "12")
+	twelveCopy := newTestPOI("Twelve Apostles", -34.001, 18.381, "sagns_id", "12")
+	targets := []POI{table, devils, lions, twelve, twelveCopy}
+	tests := []struct {
+		name   string
+		source POI
+		status MatchStatus
+		target POI
+		byKey  bool
+	}{
+		{"key match", newTestPOI("Tafelberg", -33.963, 18.404, "sagns_id", "1"), StatusExact, table, true},
+		{"key match too far", newTestPOI("Table Mountain", -33.8, 18.404, "sagns_id", "1"), StatusConflict, table, true},
+		{"duplicate key", newTestPOI("Twelve Apostles", -34.0, 18.38, "sagns_id", "12"), StatusConflict, twelve, true},
+		{"same name close by", newTestPOI("Devils Peak", -33.9516, 18.4414, "sagns_id", "2"), StatusExact, devils, false},
+		{"similar name", newTestPOI("Devil's Peak", -33.955, 18.44, "sagns_id", "2"), StatusProbable, devils, false},
+		{"candidate has other key", newTestPOI("Lions Head", -33.9356, 18.3892, "sagns_id", "3"), StatusConflict, lions, false},
+		{"nothing nearby", newTestPOI("Sneeuberg", -32.5, 19.2, "sagns_id", "4"), StatusNew, nil, false},
+		{"nearby but different name", newTestPOI("Kloof Nek", -33.956, 18.43, "sagns_id", "5"), StatusNew, nil, false},
+	}
+	c := NewConflator("sagns_id")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := c.Conflate([]POI{tt.source}, targets)
+			if len(report) != 1 {
+				t.Fatalf("got %d matches, want 1", len(report))
+			}
+			m := report[0]
+			if m.Status != tt.status {
+				t.Errorf("status = %s, want %s (%v)", m.Status, tt.status, m.Reasons)
+			}
+			if tt.target != nil && m.Target != tt.target {
+				t.Errorf("target = %v, want %v", m.Target, tt.target)
+			}
+			if m.ByKey != tt.byKey {
+				t.Errorf("ByKey = %t, want %t", m.ByKey, tt.byKey)
+			}
+			if len(m.Reasons) == 0 {
+				t.Error("no reasons given")
+			}
+			if m.Confidence < 0 || m.Confidence > 1 {
+				t.Errorf("confidence %f out of range", m.Confidence)
+			}
+		})
+	}
+}
+
+func TestConflateRequireKey(t *testing.T) {
+	devils := newTestPOI("Devils Peak", -33.9515, 18.4414)
+	c := NewConflator("sagns_id")
+	c.RequireKey = true
+	m := c.Conflate([]POI{newTestPOI("Devils Peak", -33.9516, 18.4414, "sagns_id", "2")}, []POI{devils})[0]
+	if m.Status != StatusProbable || m.Target != devils || m.ByKey {
+		t.Errorf("got %s match of %v by key %t, want probable match of %v (%v)", m.Status, m.Target, m.ByKey, devils, m.Reasons)
+	}
+}
+
+func TestConflateAltKeys(t *testing.T) {
+	full := newTestPOI("", -33.9626, 18.4039, "ref", "33/18")
+	bare := newTestPOI("", -33.9355, 18.3892, "ref", "19")
+	bareFar := newTestPOI("", -34.5, 18.3892, "ref", "20")
+	targets := []POI{full, bare, bareFar}
+	c := NewConflator("ref")
+	c.AltKeys = func(s POI) []string {
+		return []string{s.Tags()["alt"]}
+	}
+	tests := []struct {
+		name   string
+		source POI
+		status MatchStatus
+		target POI
+		byKey  bool
+	}{
+		{"key before alternate key", newTestPOI("", -33.9627, 18.4039, "ref", "33/18", "alt", "19"), StatusExact, full, true},
+		{"alternate key", newTestPOI("", -33.9356, 18.3892, "ref", "33/19", "alt", "19"), StatusProbable, bare, false},
+		{"alternate key too far", newTestPOI("", -33.9, 18.3892, "ref", "33/20", "alt", "20"), StatusNew, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := c.Conflate([]POI{tt.source}, targets)[0]
+			if m.Status != tt.status || m.Target != tt.target || m.ByKey != tt.byKey {
+				t.Errorf("got %s match of %v by key %t, want %s match of %v by key %t (%v)", m.Status, m.Target, m.ByKey, tt.status, tt.target, tt.byKey, m.Reasons)
+			}
+		})
+	}
+}
+
+func TestReportSummary(t *testing.T) {
+	r := Report{{Status: StatusNew}, {Status: StatusNew}, {Status: StatusExact}}
+	s := r.Summary()
+	if s[StatusNew] != 2 || s[StatusExact] != 1 || s[StatusConflict] != 0 {
+		t.Errorf("Summary() = %v", s)
+	}
+	if got := len(r.WithStatus(StatusNew)); got != 2 {
+		t.Errorf("WithStatus(StatusNew) returned %d matches, want 2", got)
+	}
+}
+
+func TestConflateReportOrder(t *testing.T) {
+	sources := []POI{
+		newTestPOI("Sneeuberg", -32.5, 19.2, "sagns_id", "4"),
+		newTestPOI("Tafelberg", -33.963, 18.404, "sagns_id", "1"),
+		newTestPOI("Kloof Nek", -33.956, 18.43, "sagns_id", "5"),
+	}
+	targets := []POI{newTestPOI("Table Mountain", -33.9626, 18.4039, "sagns_id", "1")}
+	r := NewConflator("sagns_id").Conflate(sources, targets)
+	got := r.Sources()
+	if len(got) != len(sources) {
+		t.Fatalf("Sources() returned %d POIs, want %d", len(got), len(sources))
+	}
+	for i := range sources {
+		if got[i] != sources[i] {
+			t.Errorf("Sources()[%d] = %v, want %v", i, got[i], sources[i])
+		}
+	}
+	if r[1].Status != StatusExact || r[0].Status != StatusNew || r[2].Status != StatusNew {
+		t.Errorf("statuses = %s, %s, %s, want new, exact, new", r[0].Status, r[1].Status, r[2].Status)
+	}
+}
+
+func TestMatchStatusString(t *testing.T) {
+	want := map[MatchStatus]string{
+		StatusNew:      "new",
+		StatusExact:    "exact",
+		StatusProbable: "probable",
+		StatusConflict: "conflict",
+		MatchStatus(9): "MatchStatus(9)",
+	}
+	for s, w := range want {
+		if s.String() != w {
+			t.Errorf("String() = %q, want %q", s.String(), w)
+		}
+	}
+}
@@ -6,6 +6,11 @@ func hsin(theta float64) float64 {
 	return math.Pow(math.Sin(theta/2), 2)
 }
 
+const earthRadius = 6378100.0
+
+// metresPerDegree is the length of one degree of latitude.
+const metresPerDegree = 2 * math.Pi * earthRadius / 360
+
 func Distance(lat1, lon1, lat2, lon2 float64) float64 {
 	var la1, lo1, la2, lo2 float64
 	la1 = lat1 * math.Pi / 180
@@ -13,10 +18,8 @@ func Distance(lat1, lon1, lat2, lon2 float64) float64 {
 	la2 = lat2 * math.Pi / 180
 	lo2 = lon2 * math.Pi / 180
 
-	const r = 6378100.0
-
 	h := hsin(la2-la1) + math.Cos(la1)*math.Cos(la2)*hsin(lo2-lo1)
-	return 2 * r * math.Asin(math.Sqrt(h))
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
 }
 
 type BBox struct {
@@ -56,6 +59,32 @@ func (c CircleBox) Contains(p POI) bool {
 	return d < c.RadiusKM*1000
 }
 
+func (c CircleBox) Bounds() BBox {
+	if c.IsZero() {
+		return BBox{}
+	}
+	dLat := c.Radius() / metresPerDegree
+	b := BBox{MinLat: c.Lat - dLat, MaxLat: c.Lat + dLat, MinLon: -180, MaxLon: 180}
+	if b.MinLat <= -90 || b.MaxLat >= 90 {
+		return b
+	}
+	dLon := dLat / math.Cos(math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat))*math.Pi/180)
+	if dLon < 180 {
+		b.MinLon, b.MaxLon = c.Lon-dLon, c.Lon+dLon
+	}
+	return b
+}
+
+func (b BBox) Bounds() BBox {
+	return b
+}
+
 type Box interface {
 	Contains(p POI) bool
 }
+
+// BoundedBox is a Box which can report a bounding box enclosing it, allowing an Index to skip cells outside of it.
+type BoundedBox interface {
+	Box
+	Bounds() BBox
+}
@@ -0,0 +1,212 @@
+package poi
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultCellSize is the size of an Index grid cell in degrees, roughly 11km of latitude.
+const DefaultCellSize = 0.1
+
+// Index is a grid based spatial index of POIs supporting box, radius and k-nearest queries.
+type Index struct {
+	cellSize float64
+	cells    map[cell][]indexEntry
+	min, max cell
+	size     int
+	// next is the sequence number of the next POI added.
+	next int
+}
+
+type cell struct {
+	lat, lon int
+}
+
+type indexEntry struct {
+	seq int
+	poi POI
+}
+
+func NewIndex(cellSize float64) *Index {
+	if cellSize <= 0 {
+		cellSize = DefaultCellSize
+	}
+	return &Index{cellSize: cellSize, cells: make(map[cell][]indexEntry, 1024)}
+}
+
+func IndexPOIs(pois []POI) *Index {
+	idx := NewIndex(DefaultCellSize)
+	for _, p := range pois {
+		idx.Add(p)
+	}
+	return idx
+}
+
+func (idx *Index) cellOf(lat, lon float64) cell {
+	return cell{lat: int(math.Floor(lat / idx.cellSize)), lon: int(math.Floor(lon / idx.cellSize))}
+}
+
+func (idx *Index) Add(p POI) {
+	c := idx.cellOf(p.Latitude(), p.Longitude())
+	if idx.size == 0 {
+		idx.min, idx.max = c, c
+	} else {
+		idx.min = cell{lat: min(idx.min.lat, c.lat), lon: min(idx.min.lon, c.lon)}
+		idx.max = cell{lat: max(idx.max.lat, c.lat), lon: max(idx.max.lon, c.lon)}
+	}
+	idx.cells[c] = append(idx.cells[c], indexEntry{seq: idx.next, poi: p})
+	idx.size++
+	idx.next++
+}
+
+// Remove removes p, which must be comparable as pointers are, from the index and reports whether it was there.
+func (idx *Index) Remove(p POI) bool {
+	c := idx.cellOf(p.Latitude(), p.Longitude())
+	entries := idx.cells[c]
+	for i, e := range entries {
+		if e.poi != p {
+			continue
+		}
+		if len(entries) == 1 {
+			delete(idx.cells, c)
+		} else {
+			idx.cells[c] = append(entries[:i], entries[i+1:]...)
+		}
+		idx.size--
+		return true
+	}
+	return false
+}
+
+func (idx *Index) Len() int {
+	return idx.size
+}
+
+// Search returns all POIs contained in b, in the order they were added.
+func (idx *Index) Search(b Box) []POI {
+	var entries []indexEntry
+	bounded, ok := b.(BoundedBox)
+	if !ok || bounded.Bounds().IsZero() {
+		entries = idx.filter(idx.min, idx.max, b)
+	} else {
+		bounds := bounded.Bounds()
+		entries = idx.filter(idx.cellOf(bounds.MinLat, bounds.MinLon), idx.cellOf(bounds.MaxLat, bounds.MaxLon), b)
+	}
+	pois := make([]POI, 0, len(entries))
+	for _, e := range entries {
+		pois = append(pois, e.poi)
+	}
+	return pois
+}
+
+// Within returns all POIs closer than radius metres to lat, lon, nearest first.
+func (idx *Index) Within(lat, lon, radius float64) []POI {
+	c := CircleBox{Lat: lat, Lon: lon, RadiusKM: radius / 1000}
+	bounds := c.Bounds()
+	entries := idx.filter(idx.cellOf(bounds.MinLat, bounds.MinLon), idx.cellOf(bounds.MaxLat, bounds.MaxLon), nil)
+	pds := &poiDists{pois: make([]POI, 0, len(entries)), distances: make([]float64, 0, len(entries))}
+	for _, e := range entries {
+		dist := Distance(lat, lon, e.poi.Latitude(), e.poi.Longitude())
+		if dist < radius {
+			pds.distances = append(pds.distances, dist)
+			pds.pois = append(pds.pois, e.poi)
+		}
+	}
+	sort.Stable(pds)
+	return pds.pois
+}
+
+// Nearest returns the k POIs closest to lat, lon, nearest first.
+func (idx *Index) Nearest(lat, lon float64, k int) []POI {
+	if k <= 0 || idx.size == 0 {
+		return nil
+	}
+	centre := idx.cellOf(lat, lon)
+	maxRing := max(
+		max(absInt(centre.lat-idx.min.lat), absInt(idx.max.lat-centre.lat)),
+		max(absInt(centre.lon-idx.min.lon), absInt(idx.max.lon-centre.lon)),
+	)
+	pds := &poiDists{}
+	for ring := 0; ring <= maxRing; ring++ {
+		for _, e := range idx.ring(centre, ring) {
+			pds.distances = append(pds.distances, Distance(lat, lon, e.poi.Latitude(), e.poi.Longitude()))
+			pds.pois = append(pds.pois, e.poi)
+		}
+		if pds.Len() < k {
+			continue
+		}
+		sort.Stable(pds)
+		if pds.distances[k-1] <= idx.coveredDistance(lat, ring) {
+			break
+		}
+	}
+	sort.Stable(pds)
+	if pds.Len() > k {
+		return pds.pois[:k]
+	}
+	return pds.pois
+}
+
+// coveredDistance is the radius around lat guaranteed to be searched once all cells up to ring have been visited.
+func (idx *Index) coveredDistance(lat float64, ring int) float64 {
+	deg := float64(ring) * idx.cellSize
+	maxLat := math.Min(math.Abs(lat)+deg, 90)
+	return deg * metresPerDegree * math.Cos(maxLat*math.Pi/180)
+}
+
+func (idx *Index) ring(centre cell, ring int) []indexEntry {
+	if ring == 0 {
+		return idx.cells[centre]
+	}
+	var entries []indexEntry
+	for lat := centre.lat - ring; lat <= centre.lat+ring; lat++ {
+		step := 1
+		if lat != centre.lat-ring && lat != centre.lat+ring {
+			step = 2 * ring
+		}
+		for lon := centre.lon - ring; lon <= centre.lon+ring; lon += step {
+			entries = append(entries, idx.cells[cell{lat: lat, lon: lon}]...)
+		}
+	}
+	return entries
+}
+
+func (idx *Index) filter(from, to cell, b Box) []indexEntry {
+	var entries []indexEntry
+	add := func(es []indexEntry) {
+		for _, e := range es {
+			if b == nil || b.Contains(e.poi) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	from = cell{lat: max(from.lat, idx.min.lat), lon: max(from.lon, idx.min.lon)}
+	to = cell{lat: min(to.lat, idx.max.lat), lon: min(to.lon, idx.max.lon)}
+	if from.lat > to.lat || from.lon > to.lon {
+		return nil
+	}
+	if (to.lat-from.lat+1)*(to.lon-from.lon+1) > len(idx.cells) {
+		for c, es := range idx.cells {
+			if c.lat >= from.lat && c.lat <= to.lat && c.lon >= from.lon && c.lon <= to.lon {
+				add(es)
+			}
+		}
+	} else {
+		for lat := from.lat; lat <= to.lat; lat++ {
+			for lon := from.lon; lon <= to.lon; lon++ {
+				add(idx.cells[cell{lat: lat, lon: lon}])
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+	return entries
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,118 @@
+package poi
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomPOIs(n int) []POI {
+	r := rand.New(rand.NewSource(1))
+	pois := make([]POI, 0, n)
+	for i := 0; i < n; i++ {
+		pois = append(pois, newTestPOI("", -35+r.Float64()*13, 16+r.Float64()*17))
+	}
+	return pois
+}
+
+func TestIndexSearch(t *testing.T) {
+	pois := randomPOIs(2000)
+	idx := IndexPOIs(pois)
+	boxes := []Box{
+		CircleBox{Lat: -33.4, Lon: 20.0, RadiusKM: 100},
+		BBox{MinLat: -30, MaxLat: -28, MinLon: 25, MaxLon: 29},
+		CircleBox{},
+		BBox{MinLat: 10, MaxLat: 11, MinLon: 10, MaxLon: 11},
+	}
+	for _, b := range boxes {
+		var want []POI
+		for _, p := range pois {
+			if b.Contains(p) {
+				want = append(want, p)
+			}
+		}
+		got := idx.Search(b)
+		if len(got) != len(want) {
+			t.Fatalf("Search(%#v) returned %d POIs, want %d", b, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Search(%#v)[%d] = %s, want %s", b, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+// bruteNearest returns the POIs closer than maxDist to poi, nearest first, by measuring the distance to each of them.
+func bruteNearest(pois []POI, poi POI, maxDist float64) []POI {
+	pds := &poiDists{}
+	for _, p := range pois {
+		dist := Distance(p.Latitude(), p.Longitude(), poi.Latitude(), poi.Longitude())
+		if dist < maxDist {
+			pds.distances = append(pds.distances, dist)
+			pds.pois = append(pds.pois, p)
+		}
+	}
+	sort.Stable(pds)
+	return pds.pois
+}
+
+func TestIndexWithinAndNearest(t *testing.T) {
+	pois := randomPOIs(2000)
+	idx := IndexPOIs(pois)
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		q := newTestPOI("", -36+r.Float64()*15, 15+r.Float64()*19)
+		want := bruteNearest(pois, q, 50000)
+		got := idx.Within(q.lat, q.lon, 50000)
+		if len(got) != len(want) {
+			t.Fatalf("Within(%s) returned %d POIs, want %d", q, len(got), len(want))
+		}
+		all := bruteNearest(pois, q, math.MaxFloat64)
+		nearest := idx.Nearest(q.lat, q.lon, 5)
+		for j := range nearest {
+			if nearest[j] != all[j] {
+				t.Fatalf("Nearest(%s)[%d] = %s, want %s", q, j, nearest[j], all[j])
+			}
+		}
+	}
+	if got := NewIndex(0).Nearest(0, 0, 3); len(got) != 0 {
+		t.Errorf("Nearest on empty index = %v", got)
+	}
+}
+
+func TestIndexNearestFewerThanK(t *testing.T) {
+	a := newTestPOI("a", -33.9, 18.4)
+	b := newTestPOI("b", -33.91, 18.41)
+	// c is many cells away, so Nearest has to search every ring to find it.
+	c := newTestPOI("c", -26.2, 28.0)
+	idx := IndexPOIs([]POI{c, b, a})
+	if idx.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", idx.Len())
+	}
+	got := idx.Nearest(-33.9, 18.4, 10)
+	if len(got) != 3 || got[0] != a || got[1] != b || got[2] != c {
+		t.Errorf("Nearest() = %v, want [a b c]", got)
+	}
+	if got := idx.Nearest(-33.9, 18.4, 0); got != nil {
+		t.Errorf("Nearest() of 0 POIs = %v", got)
+	}
+}
+
+func TestIndexRemove(t *testing.T) {
+	a, b := newTestPOI("a", -33.0, 20.0), newTestPOI("b", -33.0, 20.0)
+	idx := IndexPOIs([]POI{a, b})
+	if !idx.Remove(a) {
+		t.Fatal("Remove() did not find a")
+	}
+	if idx.Remove(a) {
+		t.Error("Remove() found a twice")
+	}
+	c := newTestPOI("c", -33.0, 20.0)
+	idx.Add(c)
+	got := idx.Within(-33.0, 20.0, 10)
+	if idx.Len() != 2 || len(got) != 2 || got[0] != b || got[1] != c {
+		t.Errorf("Within() = %v after removing a, want [b c]", got)
+	}
+}
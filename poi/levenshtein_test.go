@@ -0,0 +1,47 @@
+package poi
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"tafelberg", "tafelberg", 0},
+		{"ñandú", "nandu", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s1+"/"+tt.s2, func(t *testing.T) {
+			if got := LevenshteinDistance(tt.s1, tt.s2); got != tt.want {
+				t.Errorf("LevenshteinDistance(%q, %q) = %d, want %d", tt.s1, tt.s2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevenshteinRatio(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   float64
+	}{
+		{"abcd", "abcd", 0},
+		{"abcd", "abce", 0.25},
+		{"abcd", "wxyz", 1},
+		{"kitten", "sitting", 3.0 / 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s1+"/"+tt.s2, func(t *testing.T) {
+			if got := LevenshteinRatio(tt.s1, tt.s2); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("LevenshteinRatio(%q, %q) = %f, want %f", tt.s1, tt.s2, got, tt.want)
+			}
+		})
+	}
+}
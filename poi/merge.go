@@ -0,0 +1,175 @@
+package poi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type DiffKind int
+
+const (
+	TagUnchanged DiffKind = iota
+	// TagAdded is a key missing from the existing tags.
+	TagAdded
+	// TagChanged is a key whose existing value was replaced or extended.
+	TagChanged
+	// TagConflicting is a key with a different existing value which the policy did not allow to be changed.
+	TagConflicting
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case TagUnchanged:
+		return "unchanged"
+	case TagAdded:
+		return "added"
+	case TagChanged:
+		return "changed"
+	case TagConflicting:
+		return "conflicting"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+type TagDiff struct {
+	Key      string
+	Old, New string
+	Kind     DiffKind
+}
+
+func (d TagDiff) String() string {
+	switch d.Kind {
+	case TagAdded:
+		return fmt.Sprintf("+%s=%s", d.Key, d.New)
+	case TagChanged:
+		return fmt.Sprintf("~%s=%s (was %s)", d.Key, d.New, d.Old)
+	case TagConflicting:
+		return fmt.Sprintf("!%s=%s (proposed %s)", d.Key, d.Old, d.New)
+	}
+	return fmt.Sprintf("%s=%s", d.Key, d.Old)
+}
+
+type MergeAction int
+
+const (
+	// MergeFill sets a key only when it is missing, never overwriting an existing value.
+	MergeFill MergeAction = iota
+	// MergeOverwrite always sets a key to the proposed value.
+	MergeOverwrite
+	// MergeAppend adds the proposed value to a semicolon separated list, as is done for source.
+	MergeAppend
+	// MergeSkip never touches a key.
+	MergeSkip
+)
+
+// MergePolicy decides per key how proposed tags are merged into existing ones.
+type MergePolicy struct {
+	Actions map[string]MergeAction
+	Default MergeAction
+}
+
+// DefaultMergePolicy never overwrites existing values, fills in missing ones such as ele and adds to source.
+var DefaultMergePolicy = MergePolicy{
+	Actions: map[string]MergeAction{
+		"source": MergeAppend,
+		"fixme":  MergeSkip,
+	},
+	Default: MergeFill,
+}
+
+func (p MergePolicy) action(key string) MergeAction {
+	if a, ok := p.Actions[key]; ok {
+		return a
+	}
+	return p.Default
+}
+
+// DiffTags compares proposed tags against existing ones without applying any policy, ordered by key.
+func DiffTags(existing, proposed map[string]string) []TagDiff {
+	keys := sortedKeys(proposed)
+	diffs := make([]TagDiff, 0, len(keys))
+	for _, k := range keys {
+		d := TagDiff{Key: k, New: proposed[k]}
+		old, ok := existing[k]
+		d.Old = old
+		switch {
+		case !ok:
+			d.Kind = TagAdded
+		case old == d.New:
+			d.Kind = TagUnchanged
+		default:
+			d.Kind = TagChanged
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// MergeTags merges proposed into a copy of existing according to policy, returning the merged tags and a diff per
+// proposed key not skipped by the policy, ordered by key.
+func MergeTags(existing, proposed map[string]string, policy MergePolicy) (map[string]string, []TagDiff) {
+	merged := make(map[string]string, len(existing)+len(proposed))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	keys := sortedKeys(proposed)
+	diffs := make([]TagDiff, 0, len(keys))
+	for _, k := range keys {
+		action := policy.action(k)
+		if action == MergeSkip {
+			continue
+		}
+		d := TagDiff{Key: k, New: proposed[k]}
+		old, ok := existing[k]
+		d.Old = old
+		switch {
+		case !ok:
+			d.Kind = TagAdded
+			merged[k] = d.New
+		case old == d.New:
+			d.Kind = TagUnchanged
+		case action == MergeOverwrite:
+			d.Kind = TagChanged
+			merged[k] = d.New
+		case action == MergeAppend && listContains(old, d.New):
+			d.Kind = TagUnchanged
+		case action == MergeAppend:
+			d.Kind = TagChanged
+			d.New = old + ";" + d.New
+			merged[k] = d.New
+		default:
+			d.Kind = TagConflicting
+		}
+		diffs = append(diffs, d)
+	}
+	return merged, diffs
+}
+
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func listContains(list, val string) bool {
+	for _, v := range strings.Split(list, ";") {
+		if strings.TrimSpace(v) == val {
+			return true
+		}
+	}
+	return false
+}
+
+// Modified reports whether any diff altered the existing tags.
+func Modified(diffs []TagDiff) bool {
+	for _, d := range diffs {
+		if d.Kind == TagAdded || d.Kind == TagChanged {
+			return true
+		}
+	}
+	return false
+}
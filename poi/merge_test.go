@@ -0,0 +1,107 @@
+package poi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeTags(t *testing.T) {
+	existing := map[string]string{
+		"name":    "Tafelberg",
+		"natural": "peak",
+		"source":  "survey",
+	}
+	proposed := map[string]string{
+		"name":     "Table Mountain",
+		"natural":  "peak",
+		"ele":      "1085",
+		"source":   "sagns",
+		"fixme":    "check",
+		"sagns_id": "1",
+	}
+	merged, diffs := MergeTags(existing, proposed, DefaultMergePolicy)
+	wantMerged := map[string]string{
+		"name":     "Tafelberg",
+		"natural":  "peak",
+		"ele":      "1085",
+		"source":   "survey;sagns",
+		"sagns_id": "1",
+	}
+	if !reflect.DeepEqual(merged, wantMerged) {
+		t.Errorf("merged = %v, want %v", merged, wantMerged)
+	}
+	wantDiffs := []TagDiff{
+		{Key: "ele", New: "1085", Kind: TagAdded},
+		{Key: "name", Old: "Tafelberg", New: "Table Mountain", Kind: TagConflicting},
+		{Key: "natural", Old: "peak", New: "peak", Kind: TagUnchanged},
+		{Key: "sagns_id", New: "1", Kind: TagAdded},
+		{Key: "source", Old: "survey", New: "survey;sagns", Kind: TagChanged},
+	}
+	if !reflect.DeepEqual(diffs, wantDiffs) {
+		t.Errorf("diffs = %v, want %v", diffs, wantDiffs)
+	}
+	if !Modified(diffs) {
+		t.Error("Modified() = false, want true")
+	}
+	if existing["ele"] != "" {
+		t.Error("existing tags were modified")
+	}
+}
+
+func TestMergeTagsPolicies(t *testing.T) {
+	existing := map[string]string{"name": "Old", "source": "sagns;ngi"}
+	proposed := map[string]string{"name": "New", "source": "ngi"}
+	policy := MergePolicy{Actions: map[string]MergeAction{"name": MergeOverwrite, "source": MergeAppend}}
+	merged, diffs := MergeTags(existing, proposed, policy)
+	if merged["name"] != "New" || merged["source"] != "sagns;ngi" {
+		t.Errorf("merged = %v", merged)
+	}
+	if diffs[0].Kind != TagChanged || diffs[1].Kind != TagUnchanged {
+		t.Errorf("diffs = %v", diffs)
+	}
+	_, diffs = MergeTags(existing, map[string]string{"source": "ngi"}, policy)
+	if Modified(diffs) {
+		t.Errorf("Modified(%v) = true, want false", diffs)
+	}
+}
+
+func TestDiffTags(t *testing.T) {
+	diffs := DiffTags(map[string]string{"a": "1", "b": "2"}, map[string]string{"a": "1", "b": "3", "c": "4"})
+	want := []DiffKind{TagUnchanged, TagChanged, TagAdded}
+	for i, d := range diffs {
+		if d.Kind != want[i] {
+			t.Errorf("diff %s kind = %s, want %s", d.Key, d.Kind, want[i])
+		}
+	}
+}
+
+func TestTagDiffString(t *testing.T) {
+	tests := []struct {
+		diff TagDiff
+		want string
+	}{
+		{TagDiff{Key: "ele", New: "1085", Kind: TagAdded}, "+ele=1085"},
+		{TagDiff{Key: "source", Old: "survey", New: "survey;sagns", Kind: TagChanged}, "~source=survey;sagns (was survey)"},
+		{TagDiff{Key: "name", Old: "Tafelberg", New: "Table Mountain", Kind: TagConflicting}, "!name=Tafelberg (proposed Table Mountain)"},
+		{TagDiff{Key: "natural", Old: "peak", New: "peak", Kind: TagUnchanged}, "natural=peak"},
+	}
+	for _, tt := range tests {
+		if got := tt.diff.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestMergeTagsSkip(t *testing.T) {
+	existing := map[string]string{"fixme": "old note"}
+	merged, diffs := MergeTags(existing, map[string]string{"fixme": "check", "note": "x"}, MergePolicy{
+		Actions: map[string]MergeAction{"fixme": MergeSkip},
+		Default: MergeSkip,
+	})
+	if !reflect.DeepEqual(merged, existing) {
+		t.Errorf("merged = %v, want %v", merged, existing)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("diffs = %v, want none for skipped keys", diffs)
+	}
+}
@@ -2,8 +2,6 @@ package poi
 
 import (
 	"fmt"
-	"math"
-	"sort"
 	"strings"
 )
 
@@ -16,6 +14,12 @@ type POI interface {
 	AddTag(key, value string)
 }
 
+// Identified is implemented by POIs with an ID in the dataset they were read from.
+type Identified interface {
+	// SourceID returns the POI's ID in its source, or false if it has none.
+	SourceID() (string, bool)
+}
+
 type poiDists struct {
 	pois      []POI
 	distances []float64
@@ -71,28 +75,20 @@ outer:
 	return fuzz
 }
 
-func SelectNearest(pois []POI, poi POI, radius float64) POI {
-	if len(pois) == 1 {
-		return pois[0]
-	}
-	nearest := nearestPOIs(pois, poi, radius)
+// SelectNearest returns the POI in idx nearest to poi that lies within radius metres of it. If idx holds a single POI
+// that POI is returned regardless of its distance.
+func SelectNearest(idx *Index, poi POI, radius float64) POI {
+	nearest := idx.Nearest(poi.Latitude(), poi.Longitude(), 1)
 	if len(nearest) == 0 {
 		return nil
 	}
-	return nearest[0]
-}
-
-func nearestPOIs(pois []POI, poi POI, maxDist float64) []POI {
-	pds := &poiDists{pois: make([]POI, 0, len(pois)), distances: make([]float64, 0, len(pois))}
-	for _, p := range pois {
-		dist := Distance(p.Latitude(), p.Longitude(), poi.Latitude(), poi.Longitude())
-		if dist < maxDist {
-			pds.distances = append(pds.distances, dist)
-			pds.pois = append(pds.pois, p)
-		}
+	if idx.Len() == 1 {
+		return nearest[0]
+	}
+	if Distance(nearest[0].Latitude(), nearest[0].Longitude(), poi.Latitude(), poi.Longitude()) >= radius {
+		return nil
 	}
-	sort.Sort(pds)
-	return pds.pois
+	return nearest[0]
 }
 
 func (p *poiDists) Less(i, j int) bool {
@@ -108,28 +104,24 @@ func (p *poiDists) Len() int {
 	return len(p.distances)
 }
 
-type PartitionedPOIs map[int]map[int][]POI
-
-func CreatePartitionedPOIs() PartitionedPOIs {
-	return make(PartitionedPOIs, 10)
-}
-
-func (p PartitionedPOIs) Add(poi POI) {
-	lat := int(math.Round(poi.Latitude()))
-	lon := int(math.Round(poi.Longitude()))
-	entry, ok := p[lat]
-	if !ok {
-		p[lat] = make(map[int][]POI)
-		entry = p[lat]
-	}
-	entry[lon] = append(entry[lon], poi)
-}
-
 type Attribute struct {
 	Key   string
 	Value string
 }
 
+// ParseAttribute parses key=value, or a lone key matching any value.
+func ParseAttribute(s string) (Attribute, error) {
+	kv := strings.SplitN(s, "=", 2)
+	attr := Attribute{Key: strings.TrimSpace(kv[0])}
+	if attr.Key == "" {
+		return attr, fmt.Errorf("invalid tag %q", s)
+	}
+	if len(kv) == 2 {
+		attr.Value = strings.TrimSpace(kv[1])
+	}
+	return attr, nil
+}
+
 type Name struct {
 	Key   NameKey
 	Value string
@@ -0,0 +1,205 @@
+package poi
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+type testPOI struct {
+	name     string
+	lat, lon float64
+	tags     map[string]string
+}
+
+func newTestPOI(name string, lat, lon float64, tags ...string) *testPOI {
+	p := &testPOI{name: name, lat: lat, lon: lon, tags: make(map[string]string, len(tags)/2)}
+	for i := 0; i+1 < len(tags); i += 2 {
+		p.tags[tags[i]] = tags[i+1]
+	}
+	return p
+}
+
+func (p *testPOI) String() string {
+	return fmt.Sprintf("%s (%f, %f)", p.name, p.lat, p.lon)
+}
+
+func (p *testPOI) Latitude() float64 {
+	return p.lat
+}
+
+func (p *testPOI) Longitude() float64 {
+	return p.lon
+}
+
+func (p *testPOI) Names() []Name {
+	if p.name == "" {
+		return nil
+	}
+	return []Name{{Key: NameKeyDefault, Value: p.name}}
+}
+
+func (p *testPOI) Tags() map[string]string {
+	return p.tags
+}
+
+func (p *testPOI) AddTag(key, value string) {
+	p.tags[key] = value
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", -33.9, 18.4, -33.9, 18.4, 0},
+		{"one degree latitude", 0, 0, 1, 0, metresPerDegree},
+		{"one degree longitude at equator", 0, 0, 0, 1, metresPerDegree},
+		{"cape town to johannesburg", -33.9249, 18.4241, -26.2041, 28.0473, 1262000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > tt.want*0.01+0.001 {
+				t.Errorf("Distance() = %f, want %f", got, tt.want)
+			}
+			if back := Distance(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-6 {
+				t.Errorf("Distance() not symmetric: %f != %f", got, back)
+			}
+		})
+	}
+}
+
+func TestBBoxContains(t *testing.T) {
+	b := BBox{MinLat: -34, MaxLat: -33, MinLon: 18, MaxLon: 19}
+	tests := []struct {
+		name string
+		box  BBox
+		p    POI
+		want bool
+	}{
+		{"inside", b, newTestPOI("", -33.5, 18.5), true},
+		{"on edge", b, newTestPOI("", -33, 19), true},
+		{"north", b, newTestPOI("", -32.9, 18.5), false},
+		{"east", b, newTestPOI("", -33.5, 19.1), false},
+		{"zero box contains everything", BBox{}, newTestPOI("", 50, 50), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.box.Contains(tt.p); got != tt.want {
+				t.Errorf("Contains(%s) = %t, want %t", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircleBoxContains(t *testing.T) {
+	c := CircleBox{Lat: -33.4, Lon: 20.0, RadiusKM: 10}
+	tests := []struct {
+		name string
+		box  CircleBox
+		p    POI
+		want bool
+	}{
+		{"centre", c, newTestPOI("", -33.4, 20.0), true},
+		{"within radius", c, newTestPOI("", -33.45, 20.0), true},
+		{"outside radius", c, newTestPOI("", -33.5, 20.0), false},
+		{"zero circle contains everything", CircleBox{}, newTestPOI("", 50, 50), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.box.Contains(tt.p); got != tt.want {
+				t.Errorf("Contains(%s) = %t, want %t", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircleBoxBounds(t *testing.T) {
+	c := CircleBox{Lat: -33.4, Lon: 20.0, RadiusKM: 50}
+	b := c.Bounds()
+	for _, bearing := range []float64{0, 45, 90, 135, 180, 225, 270, 315} {
+		rad := bearing * math.Pi / 180
+		dLat := 49.9 * 1000 / metresPerDegree * math.Cos(rad)
+		dLon := 49.9 * 1000 / metresPerDegree * math.Sin(rad) / math.Cos(c.Lat*math.Pi/180)
+		p := newTestPOI("", c.Lat+dLat, c.Lon+dLon)
+		if c.Contains(p) && !b.Contains(p) {
+			t.Errorf("bounds %#v do not contain %s", b, p)
+		}
+	}
+}
+
+func TestSelectMatch(t *testing.T) {
+	table := newTestPOI("Table Mountain", -33.96, 18.40)
+	devils := newTestPOI("Devil's Peak", -33.95, 18.44)
+	lions := newTestPOI("Lion's Head", -33.93, 18.39)
+	pois := []POI{table, devils, lions}
+	tests := []struct {
+		name string
+		pois []POI
+		poi  POI
+		want POI
+	}{
+		{"no candidates", nil, table, nil},
+		{"no names picks first", pois, newTestPOI("", 0, 0), table},
+		{"exact after normalising", pois, newTestPOI("table-mountain", 0, 0), table},
+		{"contains", pois, newTestPOI("Lion's Head Peak", 0, 0), lions},
+		{"fuzzy", pois, newTestPOI("Devils Peek", 0, 0), devils},
+		{"falls back to most similar", pois, newTestPOI("Tablemount", 0, 0), table},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectMatch(tt.pois, tt.poi); got != tt.want {
+				t.Errorf("SelectMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectNearest(t *testing.T) {
+	a := newTestPOI("a", -33.0, 20.0)
+	b := newTestPOI("b", -33.01, 20.0)
+	c := newTestPOI("c", -33.1, 20.0)
+	tests := []struct {
+		name   string
+		pois   []POI
+		poi    POI
+		radius float64
+		want   POI
+	}{
+		{"single candidate regardless of distance", []POI{c}, a, 10, c},
+		{"nearest", []POI{c, b}, a, 20000, b},
+		{"none within radius", []POI{b, c}, a, 100, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectNearest(IndexPOIs(tt.pois), tt.poi, tt.radius); got != tt.want {
+				t.Errorf("SelectNearest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAttribute(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Attribute
+		wantErr bool
+	}{
+		{"natural=peak", Attribute{Key: "natural", Value: "peak"}, false},
+		{" ref ", Attribute{Key: "ref"}, false},
+		{"name = a=b", Attribute{Key: "name", Value: "a=b"}, false},
+		{"=peak", Attribute{}, true},
+		{"", Attribute{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAttribute(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAttribute(%q) error = %v, want error %t", tt.in, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseAttribute(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package sources
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecordError is a malformed record, skipped while reading unless it is only a Warning.
+type RecordError struct {
+	Line   int
+	Record []string
+	Err    error
+	// Warning is set for records which were read regardless of Err.
+	Warning bool
+}
+
+func (e *RecordError) Error() string {
+	if e.Warning {
+		return fmt.Sprintf("line %d: warning: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorReport collects the malformed records of a dataset, and the warnings about records read regardless, in the
+// order they were read.
+type ErrorReport []*RecordError
+
+func (r ErrorReport) String() string {
+	lines := make([]string, 0, len(r))
+	for _, e := range r {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Err returns nil if no records were malformed, otherwise an error summarising the report.
+func (r ErrorReport) Err() error {
+	switch len(r) {
+	case 0:
+		return nil
+	case 1:
+		return r[0]
+	}
+	return fmt.Errorf("%d malformed records, first %s", len(r), r[0])
+}
+
+// Records iterates over the records of CSV text, which may start with a byte order mark or be Windows-1252 encoded.
+// Records with the wrong number of fields or invalid quoting are skipped and collected in Errors.
+type Records struct {
+	r      *csv.Reader
+	Errors ErrorReport
+}
+
+// NewRecords reads records separated by comma, each having fields fields, as many as the first record if fields is
+// zero or any number if it is negative.
+func NewRecords(r io.Reader, comma rune, fields int) *Records {
+	cr := csv.NewReader(NewTextReader(r))
+	cr.Comma = comma
+	cr.FieldsPerRecord = fields
+	return &Records{r: cr}
+}
+
+// LazyQuotes allows quotes within unquoted fields, as in tab separated dumps which do not quote their fields.
+func (rs *Records) LazyQuotes() {
+	rs.r.LazyQuotes = true
+}
+
+// Next returns the next well-formed record and the line it starts on, or io.EOF once all have been read.
+func (rs *Records) Next() ([]string, int, error) {
+	for {
+		record, err := rs.r.Read()
+		if err == nil {
+			line, _ := rs.r.FieldPos(0)
+			return record, line, nil
+		}
+		var perr *csv.ParseError
+		if !errors.As(err, &perr) {
+			return nil, 0, err
+		}
+		rs.Skip(perr.StartLine, record, perr.Err)
+	}
+}
+
+// Skip records that the record starting on line could not be used because of err.
+func (rs *Records) Skip(line int, record []string, err error) {
+	rs.Errors = append(rs.Errors, &RecordError{Line: line, Record: record, Err: err})
+}
+
+// Warn records that the record starting on line was read despite err.
+func (rs *Records) Warn(line int, record []string, err error) {
+	rs.Errors = append(rs.Errors, &RecordError{Line: line, Record: record, Err: err, Warning: true})
+}
@@ -0,0 +1,78 @@
+package sources
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTextReader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"utf-8", "Tâfelberg’s", "Tâfelberg’s"},
+		{"bom", "\xef\xbb\xbfname;ele", "name;ele"},
+		{"bom only at start", "a\xef\xbb\xbfb", "a\ufeffb"},
+		{"windows-1252", "T\xe2felberg\x92s \x80", "Tâfelberg’s €"},
+		{"mixed", "Rivi\xe8re – Tâfel", "Rivière – Tâfel"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reading a byte at a time exercises runes split across reads.
+			got, err := ioutil.ReadAll(iotest.OneByteReader(NewTextReader(strings.NewReader(tt.in))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecords(t *testing.T) {
+	in := "\xef\xbb\xbfa;1\nb;2;extra\nc;3\"x\nd;4\n"
+	rs := NewRecords(strings.NewReader(in), ';', 2)
+	var got [][]string
+	var lines []int
+	for {
+		record, line, err := rs.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, record)
+		lines = append(lines, line)
+	}
+	if want := [][]string{{"a", "1"}, {"d", "4"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+	if want := []int{1, 4}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+	if len(rs.Errors) != 2 {
+		t.Fatalf("Errors = %v, want 2", rs.Errors)
+	}
+	if rs.Errors[0].Line != 2 || !errors.Is(rs.Errors[0], csv.ErrFieldCount) {
+		t.Errorf("first error = %v, want field count on line 2", rs.Errors[0])
+	}
+	if rs.Errors[1].Line != 3 || !errors.Is(rs.Errors[1], csv.ErrBareQuote) {
+		t.Errorf("second error = %v, want bare quote on line 3", rs.Errors[1])
+	}
+	if rs.Errors.Err() == nil {
+		t.Error("Err() of non-empty report should not be nil")
+	}
+	if (ErrorReport{}).Err() != nil {
+		t.Error("Err() of empty report should be nil")
+	}
+}
@@ -0,0 +1,95 @@
+// Package geojson encodes POIs and OSM elements as GeoJSON FeatureCollections, and reads the point features of
+// GeoJSON datasets as POIs.
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/godfried/osmimport/osm/overpass"
+	"github.com/godfried/osmimport/poi"
+)
+
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	Features []*Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// NewPoint returns a Point geometry at lat, lon.
+func NewPoint(lat, lon float64) *Geometry {
+	coords, _ := json.Marshal([]float64{lon, lat})
+	return &Geometry{Type: "Point", Coordinates: coords}
+}
+
+// NewFeature returns a point feature for p with its tags as properties. OSM elements are identified by type and ID,
+// e.g. node/123, in the feature ID and an @id property.
+func NewFeature(p poi.POI) *Feature {
+	tags := p.Tags()
+	f := &Feature{Type: "Feature", Geometry: NewPoint(p.Latitude(), p.Longitude()), Properties: make(map[string]interface{}, len(tags)+1)}
+	for k, v := range tags {
+		f.Properties[k] = v
+	}
+	if e, ok := p.(*overpass.Element); ok {
+		id := fmt.Sprintf("%s/%d", e.Type, e.ID)
+		f.ID = id
+		f.Properties["@id"] = id
+		if e.Type != "node" && e.Center == nil {
+			// Ways and relations queried without out center have no position.
+			f.Geometry = nil
+		}
+	}
+	return f
+}
+
+// NewFeatureCollection returns a collection of a point feature for each of pois.
+func NewFeatureCollection(pois []poi.POI) *FeatureCollection {
+	fc := &FeatureCollection{Type: "FeatureCollection", Features: make([]*Feature, 0, len(pois))}
+	for _, p := range pois {
+		fc.Features = append(fc.Features, NewFeature(p))
+	}
+	return fc
+}
+
+// Encode writes pois to w as a FeatureCollection.
+func Encode(w io.Writer, pois []poi.POI) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(NewFeatureCollection(pois))
+}
+
+// EncodeElements writes elements to w as a FeatureCollection.
+func EncodeElements(w io.Writer, elements []*overpass.Element) error {
+	pois := make([]poi.POI, 0, len(elements))
+	for _, e := range elements {
+		pois = append(pois, e)
+	}
+	return Encode(w, pois)
+}
+
+// WriteFile writes pois to outFile as a FeatureCollection.
+func WriteFile(pois []poi.POI, outFile string) error {
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	err = Encode(f, pois)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
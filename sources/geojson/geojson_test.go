@@ -0,0 +1,200 @@
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/godfried/osmimport/osm/overpass"
+	"github.com/godfried/osmimport/poi"
+)
+
+func TestRead(t *testing.T) {
+	r, err := Open("testdata/features.geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var pois []*POI
+	for {
+		p, err := r.NextPOI()
+		if err != nil {
+			break
+		}
+		pois = append(pois, p)
+	}
+	if len(pois) != 2 {
+		t.Fatalf("read %d POIs, want 2", len(pois))
+	}
+	want := &POI{
+		ID:  "1",
+		Lat: -33.4567890123,
+		Lon: 19.2345678901,
+		TagMap: map[string]string{
+			"name":    "Sneeuberg Hut",
+			"tourism": "wilderness_hut",
+			"beds":    "12",
+			"fee":     "yes",
+			"access":  "permit;private",
+			"contact": `{"phone":"021"}`,
+		},
+	}
+	if !reflect.DeepEqual(pois[0], want) {
+		t.Errorf("read %#v, want %#v", pois[0], want)
+	}
+	if pois[1].TagMap["fee"] != "no" || pois[1].Names()[0].Value != "Wolfberg Cracks" {
+		t.Errorf("read %#v", pois[1])
+	}
+	errs := r.Errors()
+	wantErrs := []string{
+		"line 7: unsupported geometry LineString",
+		"line 8: feature has no geometry",
+		"line 9: coordinates [-33.4 190] out of range",
+	}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("Errors() = %v, want %v", errs, wantErrs)
+	}
+	for i, e := range errs {
+		if e.Error() != wantErrs[i] {
+			t.Errorf("error %d = %q, want %q", i, e, wantErrs[i])
+		}
+	}
+	if errs[0].Record[0] != "trail-1" || errs[1].Record[0] != "feature 3" {
+		t.Errorf("skipped records %v, %v, want trail-1, feature 3", errs[0].Record, errs[1].Record)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name, json string
+	}{
+		{"not a collection", `{"type": "Feature", "geometry": null}`},
+		{"no features", `{"type": "FeatureCollection"}`},
+		{"truncated", `{"type": "FeatureCollection", "features": [{"type": "Feature"`},
+		{"array", `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReader(strings.NewReader(tt.json)).NextPOI(); err == nil {
+				t.Error("NextPOI() should fail")
+			}
+		})
+	}
+}
+
+func TestReaderLines(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`{"type": "FeatureCollection", "features": [` + "\n")
+	for i := 0; i < 1000; i++ {
+		b.WriteString(`{"type": "Feature", "geometry": {"type": "Point", "coordinates": [18.4, -33.9]}},` + "\n")
+	}
+	b.WriteString(`{"type": "Feature", "geometry": null}` + "\n]}\n")
+	r := NewReader(strings.NewReader(b.String()))
+	n := 0
+	for {
+		_, err := r.NextPOI()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if len(r.lines.newlines) > 100 {
+			t.Fatalf("kept %d newline offsets after %d features", len(r.lines.newlines), n)
+		}
+	}
+	if n != 1000 {
+		t.Errorf("read %d features, want 1000", n)
+	}
+	if errs := r.Errors(); len(errs) != 1 || errs[0].Line != 1002 {
+		t.Errorf("errors = %v, want line 1002", errs)
+	}
+}
+
+func TestPOINames(t *testing.T) {
+	p := &POI{TagMap: map[string]string{
+		"name":        "Table Mountain",
+		"name:af":     "Tafelberg",
+		"old_name":    "Taboa do Cabo",
+		"surname":     "Smith",
+		"username":    "mapper",
+		"name_suffix": "Peak",
+		"natural":     "peak",
+	}}
+	want := []poi.Name{
+		{Key: poi.NameKeyDefault, Value: "Table Mountain"},
+		{Key: poi.NameKeyAfrikaans, Value: "Tafelberg"},
+		{Key: poi.NameKeyOld, Value: "Taboa do Cabo"},
+	}
+	if got := p.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+}
+
+func TestPOITagsCopy(t *testing.T) {
+	p := &POI{TagMap: map[string]string{"natural": "peak"}}
+	p.Tags()["natural"] = "hill"
+	if p.TagMap["natural"] != "peak" {
+		t.Errorf("changing Tags() changed the POI: %v", p.TagMap)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	elements := []*overpass.Element{
+		{Type: "node", ID: 42, Lat: -33.9626, Lon: 18.4039, TagMap: map[string]string{"name": "Table Mountain", "natural": "peak"}},
+		{Type: "way", ID: 7, Center: &overpass.Center{Lat: -33.9, Lon: 18.4}, TagMap: map[string]string{"building": "hut"}},
+		{Type: "relation", ID: 8, TagMap: map[string]string{"type": "route"}},
+	}
+	var b bytes.Buffer
+	if err := EncodeElements(&b, elements); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","id":"node/42","geometry":{"type":"Point","coordinates":[18.4039,-33.9626]},"properties":{"@id":"node/42","name":"Table Mountain","natural":"peak"}},` +
+		`{"type":"Feature","id":"way/7","geometry":{"type":"Point","coordinates":[18.4,-33.9]},"properties":{"@id":"way/7","building":"hut"}},` +
+		`{"type":"Feature","id":"relation/8","geometry":null,"properties":{"@id":"relation/8","type":"route"}}]}` + "\n"
+	if b.String() != want {
+		t.Errorf("EncodeElements() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := []poi.POI{&POI{Lat: -33.25, Lon: 19.05, TagMap: map[string]string{"name": "Wolfberg Cracks", "ele": "1000"}}}
+	var b bytes.Buffer
+	if err := Encode(&b, in); err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(b.Bytes()) {
+		t.Fatalf("invalid JSON %s", b.String())
+	}
+	p, err := NewReader(&b).NextPOI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, in[0]) {
+		t.Errorf("round trip = %#v, want %#v", p, in[0])
+	}
+}
+
+func TestSourceFilter(t *testing.T) {
+	defer UseConflation("ref", nil)
+	tests := []struct {
+		key    string
+		filter []poi.Attribute
+		want   []poi.Attribute
+	}{
+		{"ref", nil, []poi.Attribute{{Key: "ref"}}},
+		{"gnis:id", nil, []poi.Attribute{{Key: "gnis:id"}}},
+		{"ref", []poi.Attribute{{Key: "natural", Value: "peak"}}, []poi.Attribute{{Key: "natural", Value: "peak"}}},
+	}
+	for _, tt := range tests {
+		UseConflation(tt.key, tt.filter)
+		if got := (Source{}).Filter(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Filter() with key %s and filter %v = %v, want %v", tt.key, tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+// POI is a point feature read from a GeoJSON dataset, tagged with its properties.
+type POI struct {
+	ID       string
+	Lat, Lon float64
+	TagMap   map[string]string
+}
+
+func (p *POI) String() string {
+	return fmt.Sprintf("%s %v", p.ID, p.TagMap)
+}
+
+func (p *POI) Latitude() float64 {
+	return p.Lat
+}
+
+func (p *POI) Longitude() float64 {
+	return p.Lon
+}
+
+// nameKeys are the keys besides name and name:<language> which OSM uses for names.
+var nameKeys = map[string]struct{}{
+	"alt_name":      {},
+	"int_name":      {},
+	"loc_name":      {},
+	"nat_name":      {},
+	"official_name": {},
+	"old_name":      {},
+	"reg_name":      {},
+	"short_name":    {},
+}
+
+// isNameKey reports whether key holds a name, as opposed to keys such as surname or name_suffix which only contain
+// "name".
+func isNameKey(key string) bool {
+	if key == "name" || strings.HasPrefix(key, "name:") {
+		return true
+	}
+	_, ok := nameKeys[key]
+	return ok
+}
+
+func (p *POI) Names() []poi.Name {
+	names := make([]poi.Name, 0, 2)
+	for key, val := range p.TagMap {
+		if isNameKey(key) {
+			names = append(names, poi.Name{Key: poi.NameKey(key), Value: val})
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Key < names[j].Key })
+	return names
+}
+
+// Tags returns a copy of the feature's tags, which can be changed without changing the POI.
+func (p *POI) Tags() map[string]string {
+	tags := make(map[string]string, len(p.TagMap))
+	for k, v := range p.TagMap {
+		tags[k] = v
+	}
+	return tags
+}
+
+// SourceID implements poi.Identified with the feature's id, which it may not have.
+func (p *POI) SourceID() (string, bool) {
+	return p.ID, p.ID != ""
+}
+
+func (p *POI) AddTag(key, value string) {
+	p.TagMap[key] = value
+}
+
+// NewPOI reads a point feature. Properties become tags: numbers as written, booleans as yes or no, arrays of
+// strings and numbers joined with semicolons and anything else as JSON. Null properties are dropped.
+func NewPOI(f *Feature) (*POI, error) {
+	if f.Geometry == nil {
+		return nil, fmt.Errorf("feature has no geometry")
+	}
+	if f.Geometry.Type != "Point" {
+		return nil, fmt.Errorf("unsupported geometry %s", f.Geometry.Type)
+	}
+	var coords []float64
+	err := json.Unmarshal(f.Geometry.Coordinates, &coords)
+	if err != nil {
+		return nil, fmt.Errorf("coordinates: %w", err)
+	}
+	if len(coords) < 2 {
+		return nil, fmt.Errorf("point has %d coordinates, want at least 2", len(coords))
+	}
+	if coords[1] < -90 || coords[1] > 90 || coords[0] < -180 || coords[0] > 180 {
+		return nil, fmt.Errorf("coordinates %v out of range", coords)
+	}
+	p := &POI{Lon: coords[0], Lat: coords[1], TagMap: make(map[string]string, len(f.Properties))}
+	if f.ID != nil {
+		p.ID = propertyValue(f.ID)
+	}
+	for k, v := range f.Properties {
+		if v == nil {
+			continue
+		}
+		p.TagMap[k] = propertyValue(v)
+	}
+	return p, nil
+}
+
+func propertyValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	case []interface{}:
+		vals := make([]string, 0, len(v))
+		for _, e := range v {
+			switch e.(type) {
+			case string, json.Number, float64:
+				vals = append(vals, propertyValue(e))
+			default:
+				return jsonValue(v)
+			}
+		}
+		return strings.Join(vals, ";")
+	}
+	return jsonValue(v)
+}
+
+func jsonValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// Reader streams the point features of a GeoJSON FeatureCollection as POIs, skipping and collecting the features
+// which are not points or are malformed.
+type Reader struct {
+	d       *json.Decoder
+	lines   *lineCounter
+	started bool
+	done    bool
+	// features counts the features read so far.
+	features int
+	errors   sources.ErrorReport
+	closer   io.Closer
+}
+
+// Open starts reading the FeatureCollection at inputFile.
+func Open(inputFile string) (*Reader, error) {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	r := NewReader(f)
+	r.closer = f
+	return r, nil
+}
+
+// NewReader reads a FeatureCollection from in.
+func NewReader(in io.Reader) *Reader {
+	lines := &lineCounter{r: sources.NewTextReader(in)}
+	d := json.NewDecoder(lines)
+	d.UseNumber()
+	return &Reader{d: d, lines: lines}
+}
+
+// start reads up to the first feature of the collection.
+func (r *Reader) start() error {
+	r.started = true
+	if err := r.expect(json.Delim('{')); err != nil {
+		return err
+	}
+	for r.d.More() {
+		tok, err := r.d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "features":
+			return r.expect(json.Delim('['))
+		case "type":
+			var typ string
+			if err := r.d.Decode(&typ); err != nil {
+				return err
+			}
+			if typ != "FeatureCollection" {
+				return fmt.Errorf("GeoJSON %s is not a FeatureCollection", typ)
+			}
+		default:
+			var skip json.RawMessage
+			if err := r.d.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("FeatureCollection has no features")
+}
+
+func (r *Reader) expect(delim json.Delim) error {
+	tok, err := r.d.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %s, got %v", delim, tok)
+	}
+	return nil
+}
+
+// NextPOI returns the next point feature, or io.EOF once all have been read.
+func (r *Reader) NextPOI() (*POI, error) {
+	if !r.started {
+		if err := r.start(); err != nil {
+			return nil, err
+		}
+	}
+	for !r.done && r.d.More() {
+		var raw json.RawMessage
+		if err := r.d.Decode(&raw); err != nil {
+			return nil, err
+		}
+		r.features++
+		line := r.lines.line(r.d.InputOffset() - int64(len(raw)))
+		f := &Feature{}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		err := d.Decode(f)
+		var p *POI
+		if err == nil {
+			p, err = NewPOI(f)
+		}
+		if err != nil {
+			record := []string{fmt.Sprintf("feature %d", r.features)}
+			if f.ID != nil {
+				record = []string{propertyValue(f.ID)}
+			}
+			r.errors = append(r.errors, &sources.RecordError{Line: line, Record: record, Err: err})
+			continue
+		}
+		return p, nil
+	}
+	r.done = true
+	return nil, io.EOF
+}
+
+// Next implements sources.Reader.
+func (r *Reader) Next() (poi.POI, error) {
+	p, err := r.NextPOI()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Errors returns the features skipped so far.
+func (r *Reader) Errors() sources.ErrorReport {
+	return r.errors
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Read reads the point features of the FeatureCollection at inputFile.
+func Read(inputFile string) ([]*POI, error) {
+	r, err := Open(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	pois := make([]*POI, 0, 1000)
+	for {
+		p, err := r.NextPOI()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		pois = append(pois, p)
+	}
+	if len(r.Errors()) > 0 {
+		log.Printf("skipped %d features in %s:\n%s", len(r.Errors()), inputFile, r.Errors())
+	}
+	return pois, nil
+}
+
+// lineCounter records where the lines of the text read through it start, to report the line of a byte offset. The
+// offsets asked for must not decrease, as the newlines before one are forgotten once it has been asked for.
+type lineCounter struct {
+	r    io.Reader
+	read int64
+	// passed counts the newlines forgotten.
+	passed   int
+	newlines []int64
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			c.newlines = append(c.newlines, c.read+int64(i))
+		}
+	}
+	c.read += int64(n)
+	return n, err
+}
+
+// line returns the line of the byte at offset, counting from 1.
+func (c *lineCounter) line(offset int64) int {
+	i := sort.Search(len(c.newlines), func(i int) bool { return c.newlines[i] >= offset })
+	c.passed += i
+	c.newlines = append(c.newlines[:0], c.newlines[i:]...)
+	return 1 + c.passed
+}
@@ -0,0 +1,52 @@
+package geojson
+
+import (
+	"sync"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+func init() {
+	sources.Register(Source{})
+}
+
+var (
+	mu        sync.RWMutex
+	dedupeKey = "ref"
+	filter    []poi.Attribute
+)
+
+// UseConflation sets the tag identifying the features already imported into OSM, and the filter selecting the OSM
+// elements they are deduplicated against. The key defaults to ref and an empty filter selects the elements with key.
+func UseConflation(key string, f []poi.Attribute) {
+	mu.Lock()
+	defer mu.Unlock()
+	dedupeKey, filter = key, f
+}
+
+// Source reads the point features of a GeoJSON FeatureCollection, deduplicated as set by UseConflation.
+type Source struct{}
+
+func (Source) Name() string {
+	return "geojson"
+}
+
+func (Source) Open(path string) (sources.Reader, error) {
+	return Open(path)
+}
+
+func (Source) DedupeKey() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dedupeKey
+}
+
+func (Source) Filter() []poi.Attribute {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(filter) == 0 {
+		return []poi.Attribute{{Key: dedupeKey}}
+	}
+	return filter
+}
@@ -0,0 +1,165 @@
+package geonames
+
+import "github.com/godfried/osmimport/poi"
+
+// Feature is a lower case GNS designation or geonames.org feature code, e.g. ppl or mt.
+type Feature string
+
+func (f Feature) filter() []poi.Attribute {
+	tags := f.OSMTags()
+	filter := make([]poi.Attribute, 0, len(tags))
+	for k, v := range tags {
+		filter = append(filter, poi.Attribute{Key: k, Value: v})
+	}
+	return filter
+}
+
+func (f Feature) OSMTags() map[string]string {
+	switch string(f) {
+	case "pplc", "ppla", "ppla2":
+		return map[string]string{
+			"place": "city",
+		}
+	case "ppla3", "ppla4":
+		return map[string]string{
+			"place": "town",
+		}
+	case "ppl":
+		return map[string]string{
+			"place": "village",
+		}
+	case "ppll":
+		return map[string]string{
+			"place": "hamlet",
+		}
+	case "pplx":
+		return map[string]string{
+			"place": "suburb",
+		}
+	case "ppls":
+		return map[string]string{
+			"place": "locality",
+		}
+	case "farm", "frm":
+		return map[string]string{
+			"place": "farm",
+		}
+	case "isl":
+		return map[string]string{
+			"place": "island",
+		}
+	case "mt", "pk", "hll":
+		return map[string]string{
+			"natural": "peak",
+		}
+	case "rdge", "mts", "hlls":
+		return map[string]string{
+			"natural": "ridge",
+		}
+	case "pass", "gap":
+		return map[string]string{
+			"mountain_pass": "yes",
+		}
+	case "vly":
+		return map[string]string{
+			"natural": "valley",
+		}
+	case "clf":
+		return map[string]string{
+			"natural": "cliff",
+		}
+	case "cape", "pt":
+		return map[string]string{
+			"natural": "cape",
+		}
+	case "bay", "cove":
+		return map[string]string{
+			"natural": "bay",
+		}
+	case "bch":
+		return map[string]string{
+			"natural": "beach",
+		}
+	case "spng":
+		return map[string]string{
+			"natural": "spring",
+		}
+	case "wll":
+		return map[string]string{
+			"man_made": "water_well",
+		}
+	case "lk", "pnd":
+		return map[string]string{
+			"natural": "water",
+		}
+	case "res":
+		return map[string]string{
+			"natural": "water",
+			"water":   "reservoir",
+		}
+	case "stm":
+		return map[string]string{
+			"waterway": "river",
+		}
+	case "stmi", "wad":
+		return map[string]string{
+			"waterway":     "stream",
+			"intermittent": "yes",
+		}
+	case "fls":
+		return map[string]string{
+			"waterway": "waterfall",
+		}
+	case "dam":
+		return map[string]string{
+			"waterway": "dam",
+		}
+	case "airp":
+		return map[string]string{
+			"aeroway": "aerodrome",
+		}
+	case "airf":
+		return map[string]string{
+			"aeroway":        "aerodrome",
+			"aerodrome:type": "airfield",
+		}
+	case "rstn":
+		return map[string]string{
+			"railway": "station",
+		}
+	case "sch":
+		return map[string]string{
+			"amenity": "school",
+		}
+	case "hsp":
+		return map[string]string{
+			"amenity": "hospital",
+		}
+	case "ch":
+		return map[string]string{
+			"amenity": "place_of_worship",
+		}
+	case "cmty":
+		return map[string]string{
+			"landuse": "cemetery",
+		}
+	case "mine":
+		return map[string]string{
+			"landuse": "quarry",
+		}
+	case "lthse":
+		return map[string]string{
+			"man_made": "lighthouse",
+		}
+	case "bdg":
+		return map[string]string{
+			"man_made": "bridge",
+		}
+	case "mstn":
+		return map[string]string{
+			"amenity":  "place_of_worship",
+			"historic": "mission",
+		}
+	}
+	return map[string]string{}
+}
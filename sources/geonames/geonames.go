@@ -1,24 +1,64 @@
 package geonames
 
 import (
-	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+
 	"log"
 )
 
+// Format identifies the layout of a geonames dump.
+type Format int
+
+const (
+	// FormatGNS is the NGA GEOnet Names Server tab separated export, which starts with a header row.
+	FormatGNS Format = iota
+	// FormatGeoNames is the geonames.org allCountries dump, which has no header and a fixed set of columns.
+	FormatGeoNames
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatGNS:
+		return "gns"
+	case FormatGeoNames:
+		return "geonames"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// IDKey is the OSM tag holding the ID of a name imported from the format.
+func (f Format) IDKey() string {
+	switch f {
+	case FormatGNS:
+		return "gns:ufi"
+	case FormatGeoNames:
+		return "geonames:id"
+	}
+	return ""
+}
+
+// geoNamesColumns is the number of columns in the geonames.org dump:
+// geonameid, name, asciiname, alternatenames, latitude, longitude, feature class, feature code, country code, cc2,
+// admin1 code, admin2 code, admin3 code, admin4 code, population, elevation, dem, timezone, modification date
+const geoNamesColumns = 19
+
 type GeoName struct {
-	Name  string
-	Type  string
-	Lat   float64
-	Lon   float64
-	ID    uint64
-	OSMID uint64
+	Name   string
+	Type   string
+	Lat    float64
+	Lon    float64
+	Ele    string
+	ID     uint64
+	OSMID  uint64
+	Format Format
+	tags   map[string]string
 }
 
 func (g GeoName) String() string {
@@ -33,96 +73,321 @@ func (g GeoName) Longitude() float64 {
 	return g.Lon
 }
 
-func (g GeoName) Names() []string {
-	return []string{g.Name}
+func (g GeoName) Names() []poi.Name {
+	if g.Name == "" {
+		return nil
+	}
+	return []poi.Name{{Key: poi.NameKeyDefault, Value: g.Name}}
+}
+
+func (g GeoName) Feature() Feature {
+	return Feature(g.Type)
 }
 
-func (g GeoName) OSMTags() map[string]string {
-	tags := map[string]string{
-		"name": g.Name,
+func (g GeoName) Tags() map[string]string {
+	tags := g.Feature().OSMTags()
+	tags["source"] = g.Format.String()
+	if g.Name != "" {
+		tags["name"] = g.Name
+	}
+	if g.Ele != "" {
+		tags["ele"] = g.Ele
 	}
 	if g.ID != 0 {
-		tags["sagns_id"] = strconv.FormatUint(g.ID, 10)
+		tags[g.Format.IDKey()] = strconv.FormatUint(g.ID, 10)
+	}
+	for k, v := range g.tags {
+		tags[k] = v
 	}
 	return tags
 }
 
-func ReadGeoNames(inputFile string, bounds common.BBox, types map[string]struct{}) (GeoNames, error) {
-	f, err := os.Open(inputFile)
+func (g *GeoName) AddTag(key, value string) {
+	if g.tags == nil {
+		g.tags = make(map[string]string, 4)
+	}
+	g.tags[key] = value
+}
+
+// SourceID implements poi.Identified with the name's ID, which it may not have.
+func (g GeoName) SourceID() (string, bool) {
+	if g.ID == 0 {
+		return "", false
+	}
+	return strconv.FormatUint(g.ID, 10), true
+}
+
+// OSMFilter selects OSM elements with the name's ID or, if it has none, the tags of its feature.
+func (g GeoName) OSMFilter() []poi.Attribute {
+	if g.ID != 0 {
+		return []poi.Attribute{{Key: g.Format.IDKey(), Value: strconv.FormatUint(g.ID, 10)}}
+	}
+	return g.Feature().filter()
+}
+
+// ReadGeoNames reads the names in bounds whose feature code is one of types, or of any type if types is empty.
+// The format of inputFile is detected from its first row.
+func ReadGeoNames(inputFile string, bounds poi.Box, types map[string]struct{}) (GeoNames, error) {
+	r, err := Open(inputFile, bounds)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	r := csv.NewReader(f)
-	r.Comma = '\t'
+	defer r.Close()
+	r.Types = types
 	geoNames := make(GeoNames, 0, 1000)
-	header, err := r.Read()
+	for {
+		g, err := r.NextGeoName()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		geoNames = append(geoNames, g)
+	}
+	if len(r.Errors()) > 0 {
+		log.Printf("skipped %d malformed records in %s:\n%s", len(r.Errors()), inputFile, r.Errors())
+	}
+	return geoNames, nil
+}
+
+// Reader streams names from a dump, skipping those outside Box, not of Types or which are malformed.
+type Reader struct {
+	records *sources.Records
+	closer  io.Closer
+	parse   parser
+	// first is the first row of a dump without a header, still to be parsed.
+	first     []string
+	firstLine int
+	// Format is the format of the dump, detected from its first row.
+	Format Format
+	// Box limits the names read to those it contains, all names are read if it is nil.
+	Box poi.Box
+	// Types limits the names read to those whose feature code it contains, names of any type are read if it is empty.
+	Types map[string]struct{}
+	// MappedOnly skips the names whose features have no OSM tags.
+	MappedOnly bool
+	// Unmapped counts the names skipped by MappedOnly.
+	Unmapped int
+}
+
+// Open starts reading the dump at inputFile.
+func Open(inputFile string, box poi.Box) (*Reader, error) {
+	f, err := os.Open(inputFile)
 	if err != nil {
 		return nil, err
 	}
-	latIndex := indexOf(header, "LAT")
-	lonIndex := indexOf(header, "LONG")
-	typeIndex := indexOf(header, "DSG")
-	nameIndex := indexOf(header, "FULL_NAME_RO")
-	for {
-		vals, err := r.Read()
+	r, err := NewReader(f, box)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %s", inputFile, err)
+	}
+	r.closer = f
+	return r, nil
+}
+
+// NewReader reads a dump from in, detecting its format from the first row.
+func NewReader(in io.Reader, box poi.Box) (*Reader, error) {
+	records := sources.NewRecords(in, '\t', -1)
+	records.LazyQuotes()
+	first, line, err := records.Next()
+	if err != nil {
 		if err == io.EOF {
-			break
+			err = fmt.Errorf("empty dump")
 		}
+		return nil, err
+	}
+	r := &Reader{records: records, Box: box}
+	if isGNSHeader(first) {
+		r.Format = FormatGNS
+		r.parse, err = newGNSParser(first)
 		if err != nil {
 			return nil, err
 		}
-		g, err := NewGeoName(vals[latIndex], vals[lonIndex], vals[nameIndex], vals[typeIndex])
+		return r, nil
+	}
+	if len(first) != geoNamesColumns {
+		return nil, fmt.Errorf("unknown format: expected a GNS header or %d columns, got %d", geoNamesColumns, len(first))
+	}
+	r.Format = FormatGeoNames
+	r.parse = parseGeoNames
+	r.first, r.firstLine = first, line
+	return r, nil
+}
+
+// NextGeoName returns the next name, or io.EOF once all have been read.
+func (r *Reader) NextGeoName() (*GeoName, error) {
+	for {
+		record, line := r.first, r.firstLine
+		if record != nil {
+			r.first = nil
+		} else {
+			var err error
+			record, line, err = r.records.Next()
+			if err != nil {
+				return nil, err
+			}
+		}
+		g, err := r.parse(record)
 		if err != nil {
-			log.Printf("could not parse GeoName record '%s': %s", vals, err)
+			r.records.Skip(line, record, err)
 			continue
 		}
-		if _, ok := types[strings.ToLower(g.Type)]; !ok || !bounds.Contains(g.Lat, g.Lon) {
+		if _, ok := r.Types[g.Type]; len(r.Types) > 0 && !ok {
 			continue
 		}
-		geoNames = append(geoNames, g)
+		if r.Box != nil && !r.Box.Contains(g) {
+			continue
+		}
+		if r.MappedOnly && len(g.Feature().OSMTags()) == 0 {
+			r.Unmapped++
+			continue
+		}
+		return g, nil
 	}
-	return geoNames, nil
+}
+
+// Next implements sources.Reader.
+func (r *Reader) Next() (poi.POI, error) {
+	g, err := r.NextGeoName()
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Errors returns the malformed records read so far.
+func (r *Reader) Errors() sources.ErrorReport {
+	return r.records.Errors
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+type parser func(vals []string) (*GeoName, error)
+
+func isGNSHeader(row []string) bool {
+	_, err := indexOf(row, "FULL_NAME_RO")
+	return err == nil
+}
+
+func newGNSParser(header []string) (parser, error) {
+	cols, err := indexesOf(header, "LAT", "LONG", "FULL_NAME_RO", "DSG")
+	if err != nil {
+		return nil, err
+	}
+	// UFI and ELEV are optional in older exports.
+	idIndex, _ := indexOf(header, "UFI")
+	eleIndex, _ := indexOf(header, "ELEV")
+	return func(vals []string) (*GeoName, error) {
+		if len(vals) != len(header) {
+			return nil, fmt.Errorf("expected %d columns, got %d", len(header), len(vals))
+		}
+		g, err := NewGeoName(vals[cols[0]], vals[cols[1]], vals[cols[2]], vals[cols[3]])
+		if err != nil {
+			return nil, err
+		}
+		g.Format = FormatGNS
+		if idIndex >= 0 {
+			g.ID, err = parseID(vals[idIndex])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if eleIndex >= 0 {
+			g.Ele = vals[eleIndex]
+		}
+		return g, nil
+	}, nil
+}
+
+func parseGeoNames(vals []string) (*GeoName, error) {
+	if len(vals) != geoNamesColumns {
+		return nil, fmt.Errorf("expected %d columns, got %d", geoNamesColumns, len(vals))
+	}
+	g, err := NewGeoName(vals[4], vals[5], vals[1], vals[7])
+	if err != nil {
+		return nil, err
+	}
+	g.Format = FormatGeoNames
+	g.ID, err = parseID(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	g.Ele = vals[15]
+	return g, nil
+}
+
+func parseID(val string) (uint64, error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return 0, nil
+	}
+	// GNS UFIs are sometimes negative, which are not stable identifiers.
+	if strings.HasPrefix(val, "-") {
+		return 0, nil
+	}
+	return strconv.ParseUint(val, 10, 64)
 }
 
 func NewGeoName(latStr, lonStr, name, tipe string) (*GeoName, error) {
-	lat, err := strconv.ParseFloat(latStr, 64)
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
 	if err != nil {
 		return nil, err
 	}
-	lon, err := strconv.ParseFloat(lonStr, 64)
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
 	if err != nil {
 		return nil, err
 	}
-	return &GeoName{Name: name, Type: strings.ToLower(tipe), Lat: lat, Lon: lon}, nil
+	return &GeoName{Name: name, Type: strings.ToLower(strings.TrimSpace(tipe)), Lat: lat, Lon: lon}, nil
 }
 
-func indexOf(vals []string, search string) int {
+func indexOf(vals []string, search string) (int, error) {
 	search = strings.ToLower(search)
 	for i, v := range vals {
 		if strings.ToLower(strings.TrimSpace(v)) == search {
-			return i
+			return i, nil
 		}
 	}
-	panic(fmt.Errorf("%s not found in %s", search, vals))
+	return -1, fmt.Errorf("column %s not found in %s", search, vals)
+}
+
+func indexesOf(vals []string, searches ...string) ([]int, error) {
+	indexes := make([]int, len(searches))
+	for i, s := range searches {
+		idx, err := indexOf(vals, s)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i] = idx
+	}
+	return indexes, nil
 }
 
 type GeoNames []*GeoName
 
-func (gs GeoNames) FindNearby(lat, lon, radius float64) GeoPoints {
-	nearby := make(GeoPoints, 0, len(gs)/10)
-	for _, g := range gs {
-		dist := common.Distance(g.Lat, g.Lon, lat, lon)
-		if dist < radius {
-			nearby = append(nearby, &GeoPoint{g, dist})
-		}
+// Index builds a spatial index over the names, to be kept and passed to FindNearby for repeated lookups.
+func (gs GeoNames) Index() *poi.Index {
+	return poi.IndexPOIs(gs.ToPOIs())
+}
+
+// FindNearby returns the names in idx within radius metres of lat, lon, nearest first.
+func FindNearby(idx *poi.Index, lat, lon, radius float64) GeoPoints {
+	within := idx.Within(lat, lon, radius)
+	nearby := make(GeoPoints, 0, len(within))
+	for _, p := range within {
+		g := p.(*GeoName)
+		nearby = append(nearby, &GeoPoint{g, poi.Distance(g.Lat, g.Lon, lat, lon)})
 	}
-	sort.Sort(nearby)
 	return nearby
 }
 
-func (gs GeoNames) ToPOIs() []common.POI {
-	pois := make([]common.POI, 0, len(gs))
+func (gs GeoNames) ToPOIs() []poi.POI {
+	pois := make([]poi.POI, 0, len(gs))
 	for _, g := range gs {
 		pois = append(pois, g)
 	}
@@ -0,0 +1,160 @@
+package geonames
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+var capeTown = poi.BBox{MinLat: -34.5, MaxLat: -33.5, MinLon: 18, MaxLon: 19}
+
+func TestReadGeoNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		bounds poi.Box
+		types  map[string]struct{}
+		want   []string
+		format Format
+	}{
+		{"gns", "testdata/gns.txt", poi.BBox{}, nil, []string{"Table Mountain", "Sea Point", "Johannesburg"}, FormatGNS},
+		{"gns in bounds", "testdata/gns.txt", capeTown, nil, []string{"Table Mountain", "Sea Point"}, FormatGNS},
+		{"gns of type", "testdata/gns.txt", poi.BBox{}, map[string]struct{}{"ppla": {}}, []string{"Johannesburg"}, FormatGNS},
+		{"geonames", "testdata/allCountries.txt", poi.BBox{}, nil, []string{"Table Mountain", "Sea Point", `Lion's "Head"`, "Johannesburg"}, FormatGeoNames},
+		{"geonames of types in bounds", "testdata/allCountries.txt", capeTown, map[string]struct{}{"mt": {}, "pk": {}}, []string{"Table Mountain", `Lion's "Head"`}, FormatGeoNames},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs, err := ReadGeoNames(tt.file, tt.bounds, tt.types)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(gs))
+			for _, g := range gs {
+				names = append(names, g.Name)
+				if g.Format != tt.format {
+					t.Errorf("%s has format %s, want %s", g.Name, g.Format, tt.format)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("ReadGeoNames() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadGeoNamesErrors(t *testing.T) {
+	for _, file := range []string{"testdata/missing.txt", "geonames_test.go"} {
+		if _, err := ReadGeoNames(file, poi.BBox{}, nil); err == nil {
+			t.Errorf("ReadGeoNames(%s) should fail", file)
+		}
+	}
+}
+
+func TestGeoNameTags(t *testing.T) {
+	tests := []struct {
+		name string
+		g    *GeoName
+		want map[string]string
+	}{
+		{
+			"gns peak",
+			&GeoName{Name: "Table Mountain", Type: "mt", Ele: "1085", ID: 1234567, Format: FormatGNS},
+			map[string]string{"natural": "peak", "name": "Table Mountain", "ele": "1085", "gns:ufi": "1234567", "source": "gns"},
+		},
+		{
+			"geonames suburb",
+			&GeoName{Name: "Sea Point", Type: "pplx", ID: 3361025, Format: FormatGeoNames},
+			map[string]string{"place": "suburb", "name": "Sea Point", "geonames:id": "3361025", "source": "geonames"},
+		},
+		{
+			"unmapped without id",
+			&GeoName{Name: "Somewhere", Type: "xyz", Format: FormatGNS},
+			map[string]string{"name": "Somewhere", "source": "gns"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.g.Tags(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	g := &GeoName{Name: "Sea Point", Type: "pplx"}
+	g.AddTag("fixme", "check")
+	if g.Tags()["fixme"] != "check" {
+		t.Errorf("AddTag() not reflected in Tags(): %v", g.Tags())
+	}
+}
+
+func TestGeoNameOSMFilter(t *testing.T) {
+	g := &GeoName{Type: "mt", ID: 42, Format: FormatGeoNames}
+	if got := g.OSMFilter(); !reflect.DeepEqual(got, []poi.Attribute{{Key: "geonames:id", Value: "42"}}) {
+		t.Errorf("OSMFilter() = %v", got)
+	}
+	g.ID = 0
+	if got := g.OSMFilter(); !reflect.DeepEqual(got, []poi.Attribute{{Key: "natural", Value: "peak"}}) {
+		t.Errorf("OSMFilter() without ID = %v", got)
+	}
+}
+
+func TestFindNearby(t *testing.T) {
+	gs, err := ReadGeoNames("testdata/allCountries.txt", poi.BBox{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nearby := FindNearby(gs.Index(), -33.93, 18.39, 5000)
+	names := make([]string, 0, len(nearby))
+	for i, g := range nearby {
+		names = append(names, g.Name)
+		if i > 0 && nearby[i-1].Distance > g.Distance {
+			t.Errorf("FindNearby() not sorted by distance: %v", nearby)
+		}
+	}
+	want := []string{`Lion's "Head"`, "Sea Point", "Table Mountain"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("FindNearby() = %v, want %v", names, want)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	row := func(id, name, lat string) string {
+		return id + "\t" + name + "\t\t\t" + lat + "\t18.4\tT\tMT\tZA" + strings.Repeat("\t", 10) + "\n"
+	}
+	dump := row("1", "Table Mountain", "-33.9") + row("2", "Nowhere", "south") + "3\tShort\n" + row("4", "Lion's Head", "-33.93")
+	r, err := NewReader(strings.NewReader(dump), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pois, err := sources.ReadAll(r, poi.BBox{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pois) != 2 {
+		t.Errorf("read %d POIs, want 2", len(pois))
+	}
+	errs := r.Errors()
+	if len(errs) != 2 || errs[0].Line != 2 || errs[1].Line != 3 {
+		t.Errorf("Errors() = %v, want lines 2 and 3", errs)
+	}
+}
+
+func TestSource(t *testing.T) {
+	r, err := Source{Format: FormatGeoNames}.Open("testdata/allCountries.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pois, err := sources.ReadAll(r, poi.BBox{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pois) != 4 {
+		t.Errorf("read %d POIs, want 4", len(pois))
+	}
+	if _, err := (Source{Format: FormatGeoNames}).Open("testdata/gns.txt"); err == nil {
+		t.Error("opening a GNS file as geonames should fail")
+	}
+}
@@ -0,0 +1,44 @@
+package geonames
+
+import (
+	"fmt"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+func init() {
+	sources.Register(Source{Format: FormatGNS})
+	sources.Register(Source{Format: FormatGeoNames})
+}
+
+// Source reads names of a single format, deduplicated against the format's ID tag.
+type Source struct {
+	Format Format
+}
+
+func (s Source) Name() string {
+	return s.Format.String()
+}
+
+func (s Source) Open(path string) (sources.Reader, error) {
+	r, err := Open(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.Format != s.Format {
+		r.Close()
+		return nil, fmt.Errorf("%s is in %s format, not %s", path, r.Format, s.Format)
+	}
+	// Names without a feature mapping cannot be tagged usefully.
+	r.MappedOnly = true
+	return r, nil
+}
+
+func (s Source) DedupeKey() string {
+	return s.Format.IDKey()
+}
+
+func (s Source) Filter() []poi.Attribute {
+	return []poi.Attribute{{Key: s.Format.IDKey()}}
+}
@@ -0,0 +1,66 @@
+package kml
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	rowPattern   = regexp.MustCompile(`(?is)<tr[^>]*>(.*?)</tr>`)
+	cellPattern  = regexp.MustCompile(`(?is)<t[dh][^>]*>(.*?)</t[dh]>`)
+	breakPattern = regexp.MustCompile(`(?i)<br\s*/?>(\s*</br>)?|\n`)
+	tagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// Fields returns the attributes of the placemark, read from the key=value lines or two column table of its
+// description and from its ExtendedData, which takes precedence. Keys are returned as written.
+func (p *Placemark) Fields() map[string]string {
+	fields := DescriptionFields(p.Description)
+	if p.ExtendedData == nil {
+		return fields
+	}
+	for _, d := range p.ExtendedData.Data {
+		fields[d.Name] = strings.TrimSpace(d.Value)
+	}
+	for _, sd := range p.ExtendedData.SchemaData {
+		for _, d := range sd.SimpleData {
+			fields[d.Name] = strings.TrimSpace(d.Value)
+		}
+	}
+	return fields
+}
+
+// DescriptionFields reads the attributes in a placemark description, either the rows of an HTML table whose first
+// cell is the key and second the value, or lines of key=value separated by line breaks.
+func DescriptionFields(desc string) map[string]string {
+	desc = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(desc), "<![CDATA["), "]]>")
+	fields := make(map[string]string, 8)
+	for _, row := range rowPattern.FindAllStringSubmatch(desc, -1) {
+		cells := cellPattern.FindAllStringSubmatch(row[1], -1)
+		if len(cells) < 2 {
+			continue
+		}
+		if k := text(cells[0][1]); k != "" {
+			fields[k] = text(cells[1][1])
+		}
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+	for _, line := range breakPattern.Split(desc, -1) {
+		vals := strings.SplitN(text(line), "=", 2)
+		if len(vals) < 2 {
+			continue
+		}
+		if k := strings.TrimSpace(vals[0]); k != "" {
+			fields[k] = strings.TrimSpace(vals[1])
+		}
+	}
+	return fields
+}
+
+// text strips the markup from s.
+func text(s string) string {
+	return strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(s, "")))
+}
@@ -0,0 +1,65 @@
+// Package kml reads the placemarks of KML and KMZ files, such as those the NGI distributes its trig beacons in.
+package kml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Placemark is a KML placemark together with where it was found.
+type Placemark struct {
+	Name         string        `xml:"name"`
+	Description  string        `xml:"description"`
+	StyleURL     string        `xml:"styleUrl"`
+	Point        *Point        `xml:"Point"`
+	ExtendedData *ExtendedData `xml:"ExtendedData"`
+	// File is the KML document the placemark was read from, the name of the file inside the archive for a KMZ.
+	File string `xml:"-"`
+	// Folders are the names of the Documents and Folders enclosing the placemark, outermost first.
+	Folders []string `xml:"-"`
+	// Line is the line the placemark starts on.
+	Line int `xml:"-"`
+}
+
+type Point struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// LatLon parses the point's lon,lat[,alt] coordinates.
+func (p Point) LatLon() (lat, lon float64, err error) {
+	val := strings.TrimSpace(p.Coordinates)
+	coords := strings.Split(val, ",")
+	if len(coords) < 2 {
+		return 0, 0, fmt.Errorf("cannot parse coordinates: %s", val)
+	}
+	lon, err = strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	lat, err = strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, err
+}
+
+type ExtendedData struct {
+	Data       []Data       `xml:"Data"`
+	SchemaData []SchemaData `xml:"SchemaData"`
+}
+
+type Data struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type SchemaData struct {
+	SchemaURL  string       `xml:"schemaUrl,attr"`
+	SimpleData []SimpleData `xml:"SimpleData"`
+}
+
+type SimpleData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
@@ -0,0 +1,154 @@
+package kml
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	r, err := Open("testdata/nested.kml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	placemarks, err := ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name    string
+		folders []string
+		line    int
+	}{
+		{"ROOT", []string{"Survey"}, 9},
+		{"TAFELBERG", []string{"Survey", "Western Cape", "Cape Town"}, 17},
+		{"KOEBERG", []string{"Survey", "Western Cape"}, 29},
+		{"SPITSKOP", []string{"Survey", "Northern Cape"}, 36},
+	}
+	if len(placemarks) != len(want) {
+		t.Fatalf("read %d placemarks, want %d", len(placemarks), len(want))
+	}
+	for i, w := range want {
+		p := placemarks[i]
+		if p.Name != w.name || !reflect.DeepEqual(p.Folders, w.folders) || p.Line != w.line || p.File != "testdata/nested.kml" {
+			t.Errorf("placemark %d = %s in %v on line %d of %s, want %s in %v on line %d", i, p.Name, p.Folders, p.Line, p.File, w.name, w.folders, w.line)
+		}
+	}
+	fields := placemarks[1].Fields()
+	wantFields := map[string]string{"Beacon Number": "33-18", "Description": "Pillar & plate", "BEACON_NUMBER": "33-18A", "Ortho Ht": "1085.2"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("Fields() = %v, want %v", fields, wantFields)
+	}
+	fields = placemarks[2].Fields()
+	wantFields = map[string]string{"Name": "KOEBERG", "Beacon Number": "33-20", "Ortho Ht": "12"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("Fields() = %v, want %v", fields, wantFields)
+	}
+	if placemarks[3].Point != nil || placemarks[3].ExtendedData != nil {
+		t.Errorf("placemark without geometry or data = %#v", placemarks[3])
+	}
+}
+
+func TestOpenKMZ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "beacons.kmz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"doc.kml", "images/trig.png", "more/other.KML"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in, err := os.Open("testdata/nested.kml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.Copy(w, in)
+		in.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	placemarks, err := ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placemarks) != 8 {
+		t.Fatalf("read %d placemarks, want 8", len(placemarks))
+	}
+	if placemarks[0].File != "doc.kml" || placemarks[4].File != "more/other.KML" {
+		t.Errorf("placemarks read from %s and %s, want doc.kml and more/other.KML", placemarks[0].File, placemarks[4].File)
+	}
+	if !reflect.DeepEqual(placemarks[4].Folders, []string{"Survey"}) {
+		t.Errorf("folders of second document = %v, want [Survey]", placemarks[4].Folders)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		kml  string
+	}{
+		{"unclosed", `<kml><Document><Placemark><name>X</name></Document></kml>`},
+		{"unsupported charset", `<?xml version="1.0" encoding="EBCDIC"?><kml></kml>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadAll(NewReader(strings.NewReader(tt.kml))); err == nil {
+				t.Error("ReadAll() should fail")
+			}
+		})
+	}
+}
+
+func TestWindows1252(t *testing.T) {
+	in := "<?xml version=\"1.0\" encoding=\"windows-1252\"?><kml><Placemark><name>Kr\xe4ntzkop</name></Placemark></kml>"
+	placemarks, err := ReadAll(NewReader(strings.NewReader(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placemarks) != 1 || placemarks[0].Name != "Kräntzkop" {
+		t.Errorf("read %v, want Kräntzkop", placemarks)
+	}
+}
+
+func TestLatLon(t *testing.T) {
+	tests := []struct {
+		val      string
+		lat, lon float64
+		wantErr  bool
+	}{
+		{"18.4039,-33.9626,0", -33.9626, 18.4039, false},
+		{" 18.4039,-33.9626\n", -33.9626, 18.4039, false},
+		{"18.4039", 0, 0, true},
+		{"x,-33.9626", 0, 0, true},
+		{"18.4039,y", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			lat, lon, err := Point{Coordinates: tt.val}.LatLon()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LatLon(%q) error = %v, wantErr %t", tt.val, err, tt.wantErr)
+			}
+			if lat != tt.lat || lon != tt.lon {
+				t.Errorf("LatLon(%q) = %f,%f, want %f,%f", tt.val, lat, lon, tt.lat, tt.lon)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package kml
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/godfried/osmimport/sources"
+)
+
+// Reader streams the placemarks of one or more KML documents, descending into nested Documents and Folders.
+type Reader struct {
+	d    *xml.Decoder
+	file string
+	// folders holds the names of the Documents and Folders currently open.
+	folders []string
+	// entries are the documents of a KMZ archive still to be read.
+	entries []*zip.File
+	doc     io.Closer
+	closer  io.Closer
+}
+
+// Open starts reading the KML file at path, or every KML document inside it in turn if it is a .kmz archive.
+func Open(path string) (*Reader, error) {
+	if strings.EqualFold(filepath.Ext(path), ".kmz") {
+		return OpenKMZ(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := NewReader(f)
+	r.file = path
+	r.closer = f
+	return r, nil
+}
+
+// OpenKMZ starts reading the KML documents inside the KMZ archive at path, in the order they are stored.
+func OpenKMZ(path string) (*Reader, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{closer: zr}
+	for _, f := range zr.File {
+		if strings.EqualFold(filepath.Ext(f.Name), ".kml") {
+			r.entries = append(r.entries, f)
+		}
+	}
+	if len(r.entries) == 0 {
+		zr.Close()
+		return nil, fmt.Errorf("%s: no KML document in archive", path)
+	}
+	return r, nil
+}
+
+// NewReader reads a single KML document from in.
+func NewReader(in io.Reader) *Reader {
+	r := &Reader{}
+	r.start(in, "")
+	return r
+}
+
+func (r *Reader) start(in io.Reader, file string) {
+	r.d = xml.NewDecoder(in)
+	r.d.CharsetReader = charsetReader
+	r.file = file
+	r.folders = r.folders[:0]
+}
+
+// charsetReader decodes the single byte encodings KML exports from Windows tools tend to declare.
+func charsetReader(charset string, in io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "windows-1252", "cp1252", "iso-8859-1", "latin1":
+		return sources.NewTextReader(in), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %s", charset)
+}
+
+// nextDocument starts reading the next document in the archive, returning io.EOF if there are none left.
+func (r *Reader) nextDocument() error {
+	if r.doc != nil {
+		r.doc.Close()
+		r.doc = nil
+	}
+	r.d = nil
+	if len(r.entries) == 0 {
+		return io.EOF
+	}
+	f := r.entries[0]
+	r.entries = r.entries[1:]
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	r.doc = rc
+	r.start(rc, f.Name)
+	return nil
+}
+
+// Next returns the next placemark, or io.EOF once all have been read.
+func (r *Reader) Next() (*Placemark, error) {
+	for {
+		if r.d == nil {
+			if err := r.nextDocument(); err != nil {
+				return nil, err
+			}
+		}
+		tok, err := r.d.Token()
+		if err == io.EOF {
+			r.d = nil
+			continue
+		}
+		if err != nil {
+			return nil, r.wrap(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p, err := r.startElement(t)
+			if err != nil {
+				return nil, r.wrap(err)
+			}
+			if p != nil {
+				return p, nil
+			}
+		case xml.EndElement:
+			if isContainer(t.Name) && len(r.folders) > 0 {
+				r.folders = r.folders[:len(r.folders)-1]
+			}
+		}
+	}
+}
+
+// startElement handles an element in the kml root or a container, returning it if it is a placemark.
+func (r *Reader) startElement(t xml.StartElement) (*Placemark, error) {
+	switch {
+	case t.Name.Local == "kml":
+		return nil, nil
+	case isContainer(t.Name):
+		r.folders = append(r.folders, "")
+		return nil, nil
+	case t.Name.Local == "name" && len(r.folders) > 0:
+		var name string
+		err := r.d.DecodeElement(&name, &t)
+		r.folders[len(r.folders)-1] = strings.TrimSpace(name)
+		return nil, err
+	case t.Name.Local == "Placemark":
+		line, _ := r.d.InputPos()
+		p := &Placemark{File: r.file, Folders: append([]string(nil), r.folders...), Line: line}
+		err := r.d.DecodeElement(p, &t)
+		if err != nil {
+			return nil, err
+		}
+		p.Name = strings.TrimSpace(p.Name)
+		return p, nil
+	}
+	return nil, r.d.Skip()
+}
+
+func isContainer(name xml.Name) bool {
+	return name.Local == "Document" || name.Local == "Folder"
+}
+
+func (r *Reader) wrap(err error) error {
+	if r.file == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", r.file, err)
+}
+
+func (r *Reader) Close() error {
+	if r.doc != nil {
+		r.doc.Close()
+		r.doc = nil
+	}
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// ReadAll reads the remaining placemarks of r.
+func ReadAll(r *Reader) ([]*Placemark, error) {
+	placemarks := make([]*Placemark, 0, 256)
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return placemarks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		placemarks = append(placemarks, p)
+	}
+}
@@ -0,0 +1,339 @@
+package sagns
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+// Geometry is the kind of OSM element a feature is expected to be mapped as.
+type Geometry string
+
+const (
+	GeometryPoint Geometry = "point"
+	GeometryLine  Geometry = "line"
+	GeometryArea  Geometry = "area"
+)
+
+// FeatureMapping describes how a SAGNS feature description is tagged and searched for in OSM.
+type FeatureMapping struct {
+	Feature string
+	Tags    map[string]string
+	// Filter selects existing OSM elements of the feature, defaulting to one derived from Tags.
+	Filter []poi.Attribute
+	// Geometry is how the feature should be mapped, POIs of line and area features are tagged with a fixme as they
+	// are imported as nodes.
+	Geometry Geometry
+}
+
+// filter returns the mapping's Filter or, if it has none, every OSM value commonly used for the feature's tags.
+func (fm *FeatureMapping) filter() []poi.Attribute {
+	if len(fm.Filter) > 0 {
+		return fm.Filter
+	}
+	filter := make([]poi.Attribute, 0, len(fm.Tags)+1)
+	for _, k := range sortedKeys(fm.Tags) {
+		switch k {
+		case "waterway":
+			filter = append(filter,
+				poi.Attribute{Key: "waterway", Value: "river"},
+				poi.Attribute{Key: "waterway", Value: "stream"},
+				poi.Attribute{Key: "waterway", Value: "canal"},
+				poi.Attribute{Key: "waterway", Value: "ditch"},
+			)
+		case "place":
+			filter = append(filter,
+				poi.Attribute{Key: "place", Value: "island"},
+				poi.Attribute{Key: "place", Value: "suburb"},
+				poi.Attribute{Key: "place", Value: "town"},
+				poi.Attribute{Key: "place", Value: "village"},
+				poi.Attribute{Key: "place", Value: "hamlet"},
+			)
+		default:
+			filter = append(filter, poi.Attribute{Key: k, Value: fm.Tags[k]})
+		}
+	}
+	return filter
+}
+
+// Mapping looks up feature mappings by normalised feature description.
+type Mapping struct {
+	features map[Feature]*FeatureMapping
+}
+
+// NewMapping indexes fms by normalised feature, failing if two normalise to the same key.
+func NewMapping(fms []FeatureMapping) (*Mapping, error) {
+	m := &Mapping{features: make(map[Feature]*FeatureMapping, len(fms))}
+	for i := range fms {
+		fm := &fms[i]
+		f := NewFeature(fm.Feature)
+		if f == "" {
+			return nil, fmt.Errorf("mapping %d has no feature", i+1)
+		}
+		if prev, ok := m.features[f]; ok {
+			return nil, fmt.Errorf("features %q and %q are the same after normalising", prev.Feature, fm.Feature)
+		}
+		m.features[f] = fm
+	}
+	return m, nil
+}
+
+func (m *Mapping) Lookup(f Feature) (*FeatureMapping, bool) {
+	fm, ok := m.features[f]
+	return fm, ok
+}
+
+func (m *Mapping) Len() int {
+	return len(m.features)
+}
+
+// LoadMapping reads feature mappings from a .json or .csv file.
+//
+// A JSON file holds an array of objects such as
+//
+//	{"feature": "Bow Lake", "tags": {"natural": "water", "water": "oxbow"}, "filter": ["natural=water"], "geometry": "area"}
+//
+// and a CSV file has a header followed by rows of feature, tags, filter and geometry, e.g.
+//
+//	Bow Lake,natural=water;water=oxbow,natural=water,area
+//
+// Filters and geometry are optional in both.
+func LoadMapping(path string) (*Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var fms []FeatureMapping
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		fms, err = decodeJSONMapping(f)
+	case ".csv":
+		fms, err = decodeCSVMapping(f)
+	default:
+		return nil, fmt.Errorf("unsupported feature mapping format %q, expected .json or .csv", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return NewMapping(fms)
+}
+
+type jsonFeatureMapping struct {
+	Feature  string            `json:"feature"`
+	Tags     map[string]string `json:"tags"`
+	Filter   []string          `json:"filter"`
+	Geometry Geometry          `json:"geometry"`
+}
+
+func decodeJSONMapping(r io.Reader) ([]FeatureMapping, error) {
+	var jfms []jsonFeatureMapping
+	if err := json.NewDecoder(r).Decode(&jfms); err != nil {
+		return nil, err
+	}
+	fms := make([]FeatureMapping, 0, len(jfms))
+	for _, jfm := range jfms {
+		fm, err := newFeatureMapping(jfm.Feature, jfm.Tags, jfm.Filter, jfm.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		fms = append(fms, fm)
+	}
+	return fms, nil
+}
+
+func decodeCSVMapping(r io.Reader) ([]FeatureMapping, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	if _, err := cr.Read(); err != nil {
+		return nil, err
+	}
+	fms := make([]FeatureMapping, 0, 128)
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return fms, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 || len(record) > 4 {
+			return nil, fmt.Errorf("row %d: expected feature, tags, filter and geometry, got %d fields", row, len(record))
+		}
+		record = append(record, "", "")
+		tags := make(map[string]string)
+		for _, a := range splitList(record[1]) {
+			attr, err := poi.ParseAttribute(a)
+			if err != nil {
+				return nil, err
+			}
+			tags[attr.Key] = attr.Value
+		}
+		fm, err := newFeatureMapping(record[0], tags, splitList(record[2]), Geometry(strings.TrimSpace(record[3])))
+		if err != nil {
+			return nil, err
+		}
+		fms = append(fms, fm)
+	}
+}
+
+func newFeatureMapping(feature string, tags map[string]string, filter []string, g Geometry) (FeatureMapping, error) {
+	fm := FeatureMapping{Feature: feature, Tags: tags, Geometry: g}
+	switch g {
+	case "", GeometryPoint, GeometryLine, GeometryArea:
+	default:
+		return fm, fmt.Errorf("feature %q has unknown geometry %q", feature, g)
+	}
+	for _, a := range filter {
+		attr, err := poi.ParseAttribute(a)
+		if err != nil {
+			return fm, err
+		}
+		fm.Filter = append(fm.Filter, attr)
+	}
+	return fm, nil
+}
+
+func splitList(s string) []string {
+	vals := make([]string, 0, 4)
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+//go:embed features_default.csv
+var defaultFeatures string
+
+// DefaultMapping is built from the features known to be in SAGNS exports, listed in features_default.csv in the format
+// read by LoadMapping.
+var DefaultMapping = mustMapping(decodeCSVMapping(strings.NewReader(defaultFeatures)))
+
+func mustMapping(fms []FeatureMapping, err error) *Mapping {
+	if err != nil {
+		panic("sagns: invalid default feature mapping: " + err.Error())
+	}
+	m, err := NewMapping(fms)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+var (
+	mappingMu sync.RWMutex
+	mapping   = DefaultMapping
+)
+
+// UseMapping replaces the mapping used to tag SAGNS features.
+func UseMapping(m *Mapping) {
+	mappingMu.Lock()
+	defer mappingMu.Unlock()
+	mapping = m
+}
+
+func currentMapping() *Mapping {
+	mappingMu.RLock()
+	defer mappingMu.RUnlock()
+	return mapping
+}
+
+// Feature is a normalised SAGNS feature description, e.g. bow_lake.
+type Feature string
+
+var featureSeparators = regexp.MustCompile(`[\s_\-]+`)
+
+// NewFeature normalises a feature description so that "Bow Lake", "bow_lake" and "BOW-LAKE" are the same feature.
+func NewFeature(description string) Feature {
+	f := strings.ToLower(strings.TrimSpace(description))
+	return Feature(featureSeparators.ReplaceAllString(f, "_"))
+}
+
+func (f Feature) mapping() (*FeatureMapping, bool) {
+	return currentMapping().Lookup(NewFeature(string(f)))
+}
+
+// Mapped reports whether the feature maps to any OSM tags.
+func (f Feature) Mapped() bool {
+	fm, ok := f.mapping()
+	return ok && len(fm.Tags) > 0
+}
+
+// OSMTags returns a copy of the tags the feature maps to, empty if it is unmapped.
+func (f Feature) OSMTags() map[string]string {
+	fm, ok := f.mapping()
+	if !ok {
+		return map[string]string{}
+	}
+	tags := make(map[string]string, len(fm.Tags)+4)
+	for k, v := range fm.Tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+func (f Feature) Geometry() Geometry {
+	if fm, ok := f.mapping(); ok {
+		return fm.Geometry
+	}
+	return ""
+}
+
+func (f Feature) filter() []poi.Attribute {
+	if fm, ok := f.mapping(); ok {
+		return fm.filter()
+	}
+	return nil
+}
+
+// UnmappedError is returned for records whose feature has no OSM tags.
+type UnmappedError struct {
+	Feature Feature
+}
+
+func (e *UnmappedError) Error() string {
+	return fmt.Sprintf("no tags available for feature %s", e.Feature)
+}
+
+// Unmapped counts the records read per feature without OSM tags.
+type Unmapped map[Feature]int
+
+// String lists the unmapped features, most frequent first.
+func (u Unmapped) String() string {
+	fs := make([]Feature, 0, len(u))
+	for f := range u {
+		fs = append(fs, f)
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		if u[fs[i]] != u[fs[j]] {
+			return u[fs[i]] > u[fs[j]]
+		}
+		return fs[i] < fs[j]
+	})
+	parts := make([]string, 0, len(fs))
+	for _, f := range fs {
+		parts = append(parts, fmt.Sprintf("%s (%d)", f, u[f]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package sagns
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// IDFile is a sidecar to a SAGNS export, recording the OSM nodes its records were uploaded as. The export itself is
+// never written to, so it can be replaced by a newer one without losing track of what has been imported.
+type IDFile struct {
+	path string
+	ids  map[uint32]uint64
+}
+
+// IDFilePath returns the path of the sidecar to the export at inputFile.
+func IDFilePath(inputFile string) string {
+	return inputFile + ".osmids.csv"
+}
+
+// LoadIDFile reads the sidecar at path, which is empty if the file does not exist yet.
+func LoadIDFile(path string) (*IDFile, error) {
+	f := &IDFile{path: path, ids: make(map[uint32]uint64)}
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = 2
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return f, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %s", path, err)
+		}
+		if line == 1 {
+			continue
+		}
+		id, err := strconv.ParseUint(record[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid sagns_id %q", path, line, record[0])
+		}
+		osmID, err := strconv.ParseUint(record[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid osmid %q", path, line, record[1])
+		}
+		f.ids[uint32(id)] = osmID
+	}
+}
+
+// Lookup returns the OSM node the record id was uploaded as.
+func (f *IDFile) Lookup(id uint32) (uint64, bool) {
+	osmID, ok := f.ids[id]
+	return osmID, ok
+}
+
+// Len returns the number of records uploaded.
+func (f *IDFile) Len() int {
+	return len(f.ids)
+}
+
+// Add records that the record id was uploaded as the node osmID, until Save is called.
+func (f *IDFile) Add(id uint32, osmID uint64) {
+	f.ids[id] = osmID
+}
+
+// Save writes the sidecar, sorted by sagns_id, replacing the previous version only once it is complete.
+func (f *IDFile) Save() error {
+	ids := make([]uint32, 0, len(f.ids))
+	for id := range f.ids {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := csv.NewWriter(tmp)
+	w.Write([]string{"sagns_id", "osmid"})
+	for _, id := range ids {
+		w.Write([]string{strconv.FormatUint(uint64(id), 10), strconv.FormatUint(f.ids[id], 10)})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
@@ -1,14 +1,13 @@
 package sagns
 
 import (
-	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
 
 	"strconv"
 
@@ -20,52 +19,161 @@ import (
 // Previous_Name, fklMagisterialDistrictID, ProvinceID, fklLanguageID, fklDisteral, Local Municipality,
 // Sound, District Municipality, fklLocalMunic, Comments, Meaning
 func Read(inputFile string) ([]*SAGNSPOI, error) {
-	f, err := os.Open(inputFile)
+	r, err := Open(inputFile, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	r := csv.NewReader(f)
-	r.FieldsPerRecord = 20
+	defer r.Close()
 	pois := make([]*SAGNSPOI, 0, 1000)
-	_, err = r.Read()
+	for {
+		p, err := r.NextPOI()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		pois = append(pois, p)
+	}
+	if len(r.Unmapped) > 0 {
+		log.Printf("skipped records with unmapped features: %s", r.Unmapped)
+	}
+	if r.Uploaded > 0 {
+		log.Printf("skipped %d records already uploaded", r.Uploaded)
+	}
+	if len(r.Errors()) > 0 {
+		log.Printf("found %d malformed records in %s:\n%s", len(r.Errors()), inputFile, r.Errors())
+	}
+	return pois, nil
+}
+
+// Reader streams POIs from a SAGNS CSV export, skipping records outside Box, with unmapped features or which are
+// malformed.
+type Reader struct {
+	records *sources.Records
+	closer  io.Closer
+	// Box limits the POIs read to those it contains, all POIs are read if it is nil.
+	Box poi.Box
+	// Unmapped counts the records skipped because their features are unmapped.
+	Unmapped Unmapped
+	// IDs skips the records already uploaded to OSM, none are skipped if it is nil.
+	IDs *IDFile
+	// Uploaded counts the records skipped because they are in IDs.
+	Uploaded int
+}
+
+// Open starts reading the export at inputFile, skipping the records its sidecar IDFile lists as uploaded.
+func Open(inputFile string, box poi.Box) (*Reader, error) {
+	ids, err := LoadIDFile(IDFilePath(inputFile))
 	if err != nil {
 		return nil, err
 	}
-	for {
-		record, err := r.Read()
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f, box)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	r.IDs = ids
+	return r, nil
+}
+
+// NewReader reads an export from in, starting with its header.
+func NewReader(in io.Reader, box poi.Box) (*Reader, error) {
+	records := sources.NewRecords(in, ',', 20)
+	if _, _, err := records.Next(); err != nil {
 		if err == io.EOF {
-			break
+			err = fmt.Errorf("missing header")
 		}
+		return nil, err
+	}
+	if len(records.Errors) > 0 {
+		return nil, fmt.Errorf("invalid header: %s", records.Errors[0])
+	}
+	return &Reader{records: records, Box: box, Unmapped: make(Unmapped)}, nil
+}
+
+// NextPOI returns the next POI, or io.EOF once all have been read.
+func (r *Reader) NextPOI() (*SAGNSPOI, error) {
+	for {
+		record, line, err := r.records.Next()
 		if err != nil {
 			return nil, err
 		}
 		p, err := NewPOI(record)
-		if err != nil {
-			log.Printf("could not parse geoname '%s': %s", record, err)
+		if uerr, ok := err.(*UnmappedError); ok {
+			r.Unmapped[uerr.Feature]++
 			continue
 		}
-		pois = append(pois, p)
+		if _, ok := err.(*DateError); ok {
+			r.records.Warn(line, record, err)
+		} else if err != nil {
+			r.records.Skip(line, record, err)
+			continue
+		}
+		if r.Box != nil && !r.Box.Contains(p) {
+			continue
+		}
+		if r.IDs != nil {
+			if _, ok := r.IDs.Lookup(p.ID()); ok {
+				r.Uploaded++
+				continue
+			}
+		}
+		return p, nil
 	}
-	return pois, nil
+}
+
+// Next implements sources.Reader.
+func (r *Reader) Next() (poi.POI, error) {
+	p, err := r.NextPOI()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Errors returns the malformed records read so far.
+func (r *Reader) Errors() sources.ErrorReport {
+	return r.records.Errors
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
 }
 
 func NewPOI(record []string) (*SAGNSPOI, error) {
-	id, err := strconv.ParseUint(record[2], 10, 64)
+	if len(record) != 20 {
+		return nil, fmt.Errorf("expected 20 fields, got %d", len(record))
+	}
+	id, err := strconv.ParseUint(record[2], 10, 32)
 	if err != nil {
-		log.Print(err)
+		return nil, fmt.Errorf("pklid: %w", err)
 	}
 	lat, err := strconv.ParseFloat(record[3], 64)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("latitude: %w", err)
 	}
 	lon, err := strconv.ParseFloat(record[4], 64)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("longitude: %w", err)
 	}
-	date, err := time.Parse("02-01-2006", record[5])
-	if err != nil {
-		log.Print(err)
+	var date time.Time
+	var dateErr error
+	if record[5] != "" {
+		// The date is informational only, so a malformed one is reported but does not lose the record.
+		date, err = time.Parse("02-01-2006", record[5])
+		if err != nil {
+			dateErr = &DateError{Err: err}
+			date = time.Time{}
+		}
 	}
 	names := make([]poi.Name, 0, 2)
 	switch {
@@ -74,9 +182,9 @@ func NewPOI(record []string) (*SAGNSPOI, error) {
 	case record[9] != "":
 		names = append(names, poi.Name{Key: poi.NameKeyOld, Value: record[9]})
 	}
-	f := Feature(strings.ReplaceAll(strings.ToLower(record[1]), " ", "_"))
-	if len(f.OSMTags()) == 0 {
-		return nil, fmt.Errorf("no tags available for feature %s", f)
+	f := NewFeature(record[1])
+	if !f.Mapped() {
+		return nil, &UnmappedError{Feature: f}
 	}
 	return &SAGNSPOI{
 		names:                names,
@@ -91,7 +199,20 @@ func NewPOI(record []string) (*SAGNSPOI, error) {
 		districtMunicipality: record[16],
 		comments:             record[18],
 		meaning:              record[19],
-	}, nil
+	}, dateErr
+}
+
+// DateError is returned along with the POI for records with a malformed date, which are kept without one.
+type DateError struct {
+	Err error
+}
+
+func (e *DateError) Error() string {
+	return fmt.Sprintf("ignoring date: %s", e.Err)
+}
+
+func (e *DateError) Unwrap() error {
+	return e.Err
 }
 
 type SAGNSPOI struct {
@@ -146,1021 +267,46 @@ func (s SAGNSPOI) Tags() map[string]string {
 	for _, n := range s.Names() {
 		tags[string(n.Key)] = n.Value
 	}
+	if g := s.feature.Geometry(); g == GeometryLine || g == GeometryArea {
+		tags["fixme"] = fmt.Sprintf("imported as a point, map as %s", g)
+	}
 	for k, v := range s.tags {
 		tags[k] = v
 	}
 	return tags
 }
 
-func (s SAGNSPOI) OSMFilter() []poi.Attribute {
-	return []poi.Attribute{{Key: "sagns_id", Value: strconv.FormatUint(uint64(s.id), 10)}} //s.feature.filter()
+// SourceID implements poi.Identified with the record's pklid.
+func (s SAGNSPOI) SourceID() (string, bool) {
+	return strconv.FormatUint(uint64(s.id), 10), true
 }
 
-func (s SAGNSPOI) String() string {
-	return fmt.Sprintf("%v", s.Tags())
+// OSMFilter selects OSM elements with the POI's sagns_id or its feature's filter.
+func (s SAGNSPOI) OSMFilter() []poi.Attribute {
+	id := poi.Attribute{Key: "sagns_id", Value: strconv.FormatUint(uint64(s.id), 10)}
+	return append([]poi.Attribute{id}, s.feature.filter()...)
 }
 
-type Feature string
-
-const (
-	FeatureAgriVillage = Feature("agrivillage")
-	FeatureAirfield    = Feature("airfield")
-	FeatureAirport     = Feature("airport")
-	FeatureArea        = Feature("area")
-	FeatureBattlefield = Feature("battlefield")
-	FeatureBay = Feature("bay")
-	FeatureBeach = Feature("beach")
-	Feature = Feature("border_post")
-	FeatureBowLake = Feature("bow_lake")
-	Feature = Feature("")
-	Feature = Feature("")
-	Feature = Feature("")
-	Feature = Feature("")
-	Feature = Feature("")
-	Feature = Feature("")
-	Feature = Feature("")
-case "Bow Lake":
-	return map[string]string{
-		"natural": "water",
-		"water":   "oxbow",
-	}
-case "Brickworks":
-	return map[string]string{
-		"industrial": "brickyard",
-	}
-case "Bridge":
-	return map[string]string{
-		"bridge": "yes",
-	}
-case "Bush Area":
-	return map[string]string{
-		"natural": "scrub",
-	}
-case "Canal":
-	return map[string]string{
-		"waterway": "canal",
-	}
-case "Cemetery":
-	return map[string]string{
-		"landuse": "cemetery",
-	}
-case "Cliff":
-	return map[string]string{
-		"natural": "cliff",
-	}
-case "Coastal Rock":
-	return map[string]string{
-		"natural": "bare_rock",
-	}
-case "Coastline":
-	return map[string]string{}
-case "Coastline_Beach":
-	return map[string]string{
-		"natural": "beach",
-	}
-case "College":
-	return map[string]string{}
-case "Cove":
-	return map[string]string{
-		"natural": "bay",
-	}
-case "Dam":
-	return map[string]string{
-		"natural": "water",
-		"water":   "reservoir",
-	}
-case "Dam Wall":
-	return map[string]string{
-		"waterway": "dam",
-	}
-case "Dock":
-	return map[string]string{"waterway": "dock"}
-case "Double Non Perennial":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "yes",
-	}
-case "Double Perennial":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "no",
-	}
-case "Drift":
-	return map[string]string{}
-case "Dry":
-	return map[string]string{
-		"natural": "desert",
-	}
-case "Dry Area":
-	return map[string]string{
-		"natural": "desert",
-	}
-case "Dry Water Course":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "yes",
-	}
-case "Forest":
-	return map[string]string{
-		"natural": "wood",
-	}
-case "Furrow":
-	return map[string]string{
-		"waterway": "ditch",
-	}
-case "Game Reserve":
-	return map[string]string{
-		"boundary":      "protected_area",
-		"landuse":       "conservation",
-		"protect_class": "1",
-	}
-case "Gorge":
-	return map[string]string{
-		"natural": "stream",
-	}
-case "Group of huts":
-	return map[string]string{
-		"place": "hamlet",
-	}
-case "Guard Post":
-	return map[string]string{
-		"barrier": "border_control",
-	}
-case "Harbour":
-	return map[string]string{
-		"harbour": "yes",
-	}
-case "Heritage resource":
-	return map[string]string{}
-case "Hill":
-	return map[string]string{
-		"natural": "peak",
-	}
-case "Historical":
-	return map[string]string{
-		"historic": "yes",
-	}
-case "Holy Grave":
-	return map[string]string{
-		"historic": "tomb",
-	}
-case "Hospital":
-	return map[string]string{
-		"amenity": "hospital",
-	}
-case "Hotel":
-	return map[string]string{
-		"tourism": "hotel",
-	}
-case "Industrial":
-	return map[string]string{
-		"landuse": "industrial",
-	}
-case "Interchange":
-	return map[string]string{
-		"highway": "motorway junction",
-	}
-case "Island":
-	return map[string]string{
-		"place": "island",
-	}
-case "Island Real":
-	return map[string]string{
-		"place": "island",
-	}
-case "Junction":
-	return map[string]string{
-		"highway": "motorway junction",
-	}
-case "Kloof":
-	return map[string]string{
-		"waterway": "stream",
-	}
-case "Kop":
-	return map[string]string{
-		"natural": "peak",
-	}
-case "Lagoon":
-	return map[string]string{
-		"natural": "water",
-		"water":   "lagoon",
-	}
-case "Lake":
-	return map[string]string{
-		"natural": "water",
-		"water":   "lake",
-	}
-case "Lake Vlei":
-	return map[string]string{
-		"natural": "water",
-		"water":   "lake",
-	}
-case "Land Development":
-	return map[string]string{
-		"landuse": "construction",
-	}
-case "Landing Strip":
-	return map[string]string{
-		"aeroway":        "aerodrome",
-		"aerodrome:type": "airfield",
-	}
-case "Lighthouse/Marine_Beacon":
-	return map[string]string{
-		"man_made": "lighthouse",
-	}
-case "Main":
-	return map[string]string{}
-case "Marsh Vlei":
-	return map[string]string{
-		"natural": "wetland",
-		"wetland": "marsh",
-	}
-case "Mission":
-	return map[string]string{
-		"place": "hamlet",
-	}
-case "Mountain":
-	return map[string]string{
-		"natural": "peak",
-	}
-case "Mountain Peak":
-	return map[string]string{
-		"natural": "peak",
-	}
-case "Mountain Range":
-	return map[string]string{
-		"natural": "mountain_range",
-	}
-case "Mouth":
-	return map[string]string{
-		"waterway": "river",
-	}
-case "Museum":
-	return map[string]string{
-		"tourism": "museum",
-	}
-case "Nature Reserve":
-	return map[string]string{
-		"boundary":      "protected_area",
-		"landuse":       "conservation",
-		"protect_class": "1",
-	}
-case "Non Perennial":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "yes",
-	}
-case "Non_Perennial":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "yes",
-	}
-case "Observatory":
-	return map[string]string{
-		"landuse":  "observatory",
-		"man_made": "telescope",
-	}
-case "Ocean":
-	return map[string]string{}
-case "Other":
-	return map[string]string{}
-case "Pan":
-	return map[string]string{
-		"natural": "desert",
-	}
-case "Pass":
-	return map[string]string{
-		"mountain_pass": "yes",
-	}
-case "Pass Neks":
-	return map[string]string{
-		"natural": "saddle",
-	}
-case "Patrol Post":
-	return map[string]string{
-		"barrier": "border_control",
-	}
-case "Peak":
-	return map[string]string{
-		"natural": "peak",
-	}
-case "Perennial":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "no",
-	}
-case "Plain":
-	return map[string]string{
-		"natural": "grassland",
-	}
-case "Plantation":
-	return map[string]string{
-		"landuse": "forest",
-	}
-case "Plateau":
-	return map[string]string{
-		"natural": "plateau",
-	}
-case "Police_Station":
-	return map[string]string{
-		"amenity": "police",
-	}
-case "Post Office":
-	return map[string]string{
-		"amenity": "post_office",
-	}
-case "Power station":
-	return map[string]string{
-		"power": "substation",
-	}
-case "Prison":
-	return map[string]string{
-		"amenity": "prison",
-	}
-case "Protected Area":
-	return map[string]string{
-		"boundary":      "protected_area",
-		"landuse":       "conservation",
-		"protect_class": "1",
-	}
-case "Quarry":
-	return map[string]string{
-		"landuse": "quarry",
-	}
-case "Railway":
-	return map[string]string{
-		"railway": "rail",
-	}
-case "Railway Station":
-	return map[string]string{
-		"railway": "station",
-	}
-case "Railway Tunnel":
-	return map[string]string{
-		"railway": "rail",
-		"tunnel":  "yes",
-	}
-case "Research Centre":
-	return map[string]string{
-		"amenity": "research_institute",
-	}
-case "Research Institute":
-	return map[string]string{
-		"amenity": "research_institute",
-	}
-case "Residential Town":
-	return map[string]string{
-		"place": "town",
-	}
-case "Residential Township":
-	return map[string]string{
-		"place": "town",
-	}
-case "Ridge":
-	return map[string]string{
-		"natural": "ridge",
-	}
-case "River (not specified)":
-	return map[string]string{
-		"waterway": "river",
-	}
-case "River Bend":
-	return map[string]string{
-		"waterway": "river",
-	}
-case "Road":
-	return map[string]string{
-		"highway": "unclassified",
-	}
-case "Rock":
-	return map[string]string{
-		"natural": "bare_rock",
-	}
-case "Rock Outcrop":
-	return map[string]string{
-		"natural": "bare_rock",
-	}
-case "Ruin":
-	return map[string]string{
-		"historic": "ruin",
-	}
-case "Sandy Area":
-	return map[string]string{
-		"natural": "sand",
-	}
-case "Sawmill":
-	return map[string]string{
-		"craft": "sawmill",
-	}
-case "School":
-	return map[string]string{
-		"school": "yes",
-	}
-case "Settlement":
-	return map[string]string{
-		"place": "village",
-	}
-case "Single Non Perennial":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "yes",
-	}
-case "Single Perennial":
-	return map[string]string{
-		"waterway":     "river",
-		"intermittent": "no",
-	}
-case "Siphon":
-	return map[string]string{
-		"waterway": "canal",
-	}
-case "Spa":
-	return map[string]string{
-		"amenity":   "public_bath",
-		"bath:type": "thermal",
-	}
-case "State":
-	return map[string]string{
-		"landuse": "forest",
-	}
-case "Station":
-	return map[string]string{
-		"railway": "station",
-	}
-case "Studam":
-	return map[string]string{
-		"waterway": "weir",
-	}
-case "Tower":
-	return map[string]string{
-		"man_made": "tower",
-	}
-case "Town":
-	return map[string]string{
-		"place": "town",
-	}
-case "Township":
-	return map[string]string{
-		"place": "town",
-	}
-case "Trail Hiking":
-	return map[string]string{
-		"highway": "path",
-	}
-case "Tunnel":
-	return map[string]string{
-		"tunnel": "yes",
-	}
-case "Urban Area":
-	return map[string]string{
-		"place": "suburb",
-	}
-case "Valley":
-	return map[string]string{
-		"natural": "valley",
-	}
-case "Village":
-	return map[string]string{
-		"place": "village",
-	}
-case "Village Settlement":
-	return map[string]string{
-		"place": "village",
-	}
-case "Water":
-	return map[string]string{
-		"natural": "water",
-	}
-case "Weir":
-	return map[string]string{
-		"waterway": "weir",
-	}
-case "Yard":
-	return map[string]string{}
-	FeatureAgriVillage = Feature("agrivillage")
-	FeatureAgriVillage = Feature("agrivillage")
-)
-
-func (f Feature) filter() []poi.Attribute {
-	tags := f.OSMTags()
-	filter := make([]poi.Attribute, 0, len(tags)+1)
-	for k, v := range tags {
-		switch k {
-		case "waterway":
-			filter = append(filter,
-				poi.Attribute{Key: "waterway", Value: "river"},
-				poi.Attribute{Key: "waterway", Value: "stream"},
-				poi.Attribute{Key: "waterway", Value: "canal"},
-				poi.Attribute{Key: "waterway", Value: "ditch"},
-			)
-		case "place":
-			filter = append(filter,
-				poi.Attribute{Key: "place", Value: "island"},
-				poi.Attribute{Key: "place", Value: "suburb"},
-				poi.Attribute{Key: "place", Value: "town"},
-				poi.Attribute{Key: "place", Value: "village"},
-				poi.Attribute{Key: "place", Value: "hamlet"},
-			)
-		default:
-			filter = append(filter, poi.Attribute{Key: k, Value: v})
+// QueryFilter selects the OSM elements to conflate pois against: those imported from SAGNS along with those matching
+// the filter of any of the POIs' features.
+func QueryFilter(pois []poi.POI) []poi.Attribute {
+	filter := []poi.Attribute{{Key: "sagns_id"}}
+	seen := map[poi.Attribute]bool{filter[0]: true}
+	for _, p := range pois {
+		s, ok := p.(*SAGNSPOI)
+		if !ok {
+			continue
+		}
+		for _, a := range s.feature.filter() {
+			if !seen[a] {
+				seen[a] = true
+				filter = append(filter, a)
+			}
 		}
 	}
 	return filter
 }
 
-func (f Feature) OSMTags() map[string]string {
-	switch string(f) {
-	case "Agrivillage":
-		return map[string]string{
-			"place": "village",
-		}
-	case "Airfield":
-		return map[string]string{
-			"aeroway":        "aerodrome",
-			"aerodrome:type": "airfield",
-		}
-	case "Airport":
-		return map[string]string{
-			"aeroway": "aerodrome",
-		}
-	case "Area":
-		return map[string]string{}
-	case "Battlefield":
-		return map[string]string{
-			"historic": "battlefield",
-		}
-	case "Bay":
-		return map[string]string{
-			"natural": "bay",
-		}
-	case "Beach":
-		return map[string]string{
-			"natural": "beach",
-		}
-	case "Border Post":
-		return map[string]string{
-			"barrier": "border_control",
-		}
-	case "Bow Lake":
-		return map[string]string{
-			"natural": "water",
-			"water":   "oxbow",
-		}
-	case "Brickworks":
-		return map[string]string{
-			"industrial": "brickyard",
-		}
-	case "Bridge":
-		return map[string]string{
-			"bridge": "yes",
-		}
-	case "Bush Area":
-		return map[string]string{
-			"natural": "scrub",
-		}
-	case "Canal":
-		return map[string]string{
-			"waterway": "canal",
-		}
-	case "Cemetery":
-		return map[string]string{
-			"landuse": "cemetery",
-		}
-	case "Cliff":
-		return map[string]string{
-			"natural": "cliff",
-		}
-	case "Coastal Rock":
-		return map[string]string{
-			"natural": "bare_rock",
-		}
-	case "Coastline":
-		return map[string]string{}
-	case "Coastline_Beach":
-		return map[string]string{
-			"natural": "beach",
-		}
-	case "College":
-		return map[string]string{}
-	case "Cove":
-		return map[string]string{
-			"natural": "bay",
-		}
-	case "Dam":
-		return map[string]string{
-			"natural": "water",
-			"water":   "reservoir",
-		}
-	case "Dam Wall":
-		return map[string]string{
-			"waterway": "dam",
-		}
-	case "Dock":
-		return map[string]string{"waterway": "dock"}
-	case "Double Non Perennial":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "yes",
-		}
-	case "Double Perennial":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "no",
-		}
-	case "Drift":
-		return map[string]string{}
-	case "Dry":
-		return map[string]string{
-			"natural": "desert",
-		}
-	case "Dry Area":
-		return map[string]string{
-			"natural": "desert",
-		}
-	case "Dry Water Course":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "yes",
-		}
-	case "Forest":
-		return map[string]string{
-			"natural": "wood",
-		}
-	case "Furrow":
-		return map[string]string{
-			"waterway": "ditch",
-		}
-	case "Game Reserve":
-		return map[string]string{
-			"boundary":      "protected_area",
-			"landuse":       "conservation",
-			"protect_class": "1",
-		}
-	case "Gorge":
-		return map[string]string{
-			"natural": "stream",
-		}
-	case "Group of huts":
-		return map[string]string{
-			"place": "hamlet",
-		}
-	case "Guard Post":
-		return map[string]string{
-			"barrier": "border_control",
-		}
-	case "Harbour":
-		return map[string]string{
-			"harbour": "yes",
-		}
-	case "Heritage resource":
-		return map[string]string{}
-	case "Hill":
-		return map[string]string{
-			"natural": "peak",
-		}
-	case "Historical":
-		return map[string]string{
-			"historic": "yes",
-		}
-	case "Holy Grave":
-		return map[string]string{
-			"historic": "tomb",
-		}
-	case "Hospital":
-		return map[string]string{
-			"amenity": "hospital",
-		}
-	case "Hotel":
-		return map[string]string{
-			"tourism": "hotel",
-		}
-	case "Industrial":
-		return map[string]string{
-			"landuse": "industrial",
-		}
-	case "Interchange":
-		return map[string]string{
-			"highway": "motorway junction",
-		}
-	case "Island":
-		return map[string]string{
-			"place": "island",
-		}
-	case "Island Real":
-		return map[string]string{
-			"place": "island",
-		}
-	case "Junction":
-		return map[string]string{
-			"highway": "motorway junction",
-		}
-	case "Kloof":
-		return map[string]string{
-			"waterway": "stream",
-		}
-	case "Kop":
-		return map[string]string{
-			"natural": "peak",
-		}
-	case "Lagoon":
-		return map[string]string{
-			"natural": "water",
-			"water":   "lagoon",
-		}
-	case "Lake":
-		return map[string]string{
-			"natural": "water",
-			"water":   "lake",
-		}
-	case "Lake Vlei":
-		return map[string]string{
-			"natural": "water",
-			"water":   "lake",
-		}
-	case "Land Development":
-		return map[string]string{
-			"landuse": "construction",
-		}
-	case "Landing Strip":
-		return map[string]string{
-			"aeroway":        "aerodrome",
-			"aerodrome:type": "airfield",
-		}
-	case "Lighthouse/Marine_Beacon":
-		return map[string]string{
-			"man_made": "lighthouse",
-		}
-	case "Main":
-		return map[string]string{}
-	case "Marsh Vlei":
-		return map[string]string{
-			"natural": "wetland",
-			"wetland": "marsh",
-		}
-	case "Mission":
-		return map[string]string{
-			"place": "hamlet",
-		}
-	case "Mountain":
-		return map[string]string{
-			"natural": "peak",
-		}
-	case "Mountain Peak":
-		return map[string]string{
-			"natural": "peak",
-		}
-	case "Mountain Range":
-		return map[string]string{
-			"natural": "mountain_range",
-		}
-	case "Mouth":
-		return map[string]string{
-			"waterway": "river",
-		}
-	case "Museum":
-		return map[string]string{
-			"tourism": "museum",
-		}
-	case "Nature Reserve":
-		return map[string]string{
-			"boundary":      "protected_area",
-			"landuse":       "conservation",
-			"protect_class": "1",
-		}
-	case "Non Perennial":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "yes",
-		}
-	case "Non_Perennial":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "yes",
-		}
-	case "Observatory":
-		return map[string]string{
-			"landuse":  "observatory",
-			"man_made": "telescope",
-		}
-	case "Ocean":
-		return map[string]string{}
-	case "Other":
-		return map[string]string{}
-	case "Pan":
-		return map[string]string{
-			"natural": "desert",
-		}
-	case "Pass":
-		return map[string]string{
-			"mountain_pass": "yes",
-		}
-	case "Pass Neks":
-		return map[string]string{
-			"natural": "saddle",
-		}
-	case "Patrol Post":
-		return map[string]string{
-			"barrier": "border_control",
-		}
-	case "Peak":
-		return map[string]string{
-			"natural": "peak",
-		}
-	case "Perennial":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "no",
-		}
-	case "Plain":
-		return map[string]string{
-			"natural": "grassland",
-		}
-	case "Plantation":
-		return map[string]string{
-			"landuse": "forest",
-		}
-	case "Plateau":
-		return map[string]string{
-			"natural": "plateau",
-		}
-	case "Police_Station":
-		return map[string]string{
-			"amenity": "police",
-		}
-	case "Post Office":
-		return map[string]string{
-			"amenity": "post_office",
-		}
-	case "Power station":
-		return map[string]string{
-			"power": "substation",
-		}
-	case "Prison":
-		return map[string]string{
-			"amenity": "prison",
-		}
-	case "Protected Area":
-		return map[string]string{
-			"boundary":      "protected_area",
-			"landuse":       "conservation",
-			"protect_class": "1",
-		}
-	case "Quarry":
-		return map[string]string{
-			"landuse": "quarry",
-		}
-	case "Railway":
-		return map[string]string{
-			"railway": "rail",
-		}
-	case "Railway Station":
-		return map[string]string{
-			"railway": "station",
-		}
-	case "Railway Tunnel":
-		return map[string]string{
-			"railway": "rail",
-			"tunnel":  "yes",
-		}
-	case "Research Centre":
-		return map[string]string{
-			"amenity": "research_institute",
-		}
-	case "Research Institute":
-		return map[string]string{
-			"amenity": "research_institute",
-		}
-	case "Residential Town":
-		return map[string]string{
-			"place": "town",
-		}
-	case "Residential Township":
-		return map[string]string{
-			"place": "town",
-		}
-	case "Ridge":
-		return map[string]string{
-			"natural": "ridge",
-		}
-	case "River (not specified)":
-		return map[string]string{
-			"waterway": "river",
-		}
-	case "River Bend":
-		return map[string]string{
-			"waterway": "river",
-		}
-	case "Road":
-		return map[string]string{
-			"highway": "unclassified",
-		}
-	case "Rock":
-		return map[string]string{
-			"natural": "bare_rock",
-		}
-	case "Rock Outcrop":
-		return map[string]string{
-			"natural": "bare_rock",
-		}
-	case "Ruin":
-		return map[string]string{
-			"historic": "ruin",
-		}
-	case "Sandy Area":
-		return map[string]string{
-			"natural": "sand",
-		}
-	case "Sawmill":
-		return map[string]string{
-			"craft": "sawmill",
-		}
-	case "School":
-		return map[string]string{
-			"school": "yes",
-		}
-	case "Settlement":
-		return map[string]string{
-			"place": "village",
-		}
-	case "Single Non Perennial":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "yes",
-		}
-	case "Single Perennial":
-		return map[string]string{
-			"waterway":     "river",
-			"intermittent": "no",
-		}
-	case "Siphon":
-		return map[string]string{
-			"waterway": "canal",
-		}
-	case "Spa":
-		return map[string]string{
-			"amenity":   "public_bath",
-			"bath:type": "thermal",
-		}
-	case "State":
-		return map[string]string{
-			"landuse": "forest",
-		}
-	case "Station":
-		return map[string]string{
-			"railway": "station",
-		}
-	case "Studam":
-		return map[string]string{
-			"waterway": "weir",
-		}
-	case "Tower":
-		return map[string]string{
-			"man_made": "tower",
-		}
-	case "Town":
-		return map[string]string{
-			"place": "town",
-		}
-	case "Township":
-		return map[string]string{
-			"place": "town",
-		}
-	case "Trail Hiking":
-		return map[string]string{
-			"highway": "path",
-		}
-	case "Tunnel":
-		return map[string]string{
-			"tunnel": "yes",
-		}
-	case "Urban Area":
-		return map[string]string{
-			"place": "suburb",
-		}
-	case "Valley":
-		return map[string]string{
-			"natural": "valley",
-		}
-	case "Village":
-		return map[string]string{
-			"place": "village",
-		}
-	case "Village Settlement":
-		return map[string]string{
-			"place": "village",
-		}
-	case "Water":
-		return map[string]string{
-			"natural": "water",
-		}
-	case "Weir":
-		return map[string]string{
-			"waterway": "weir",
-		}
-	case "Yard":
-		return map[string]string{}
-	default:
-		log.Printf("unknown feature %s", f)
-		return map[string]string{}
-	}
+func (s SAGNSPOI) String() string {
+	return fmt.Sprintf("%v", s.Tags())
 }
@@ -0,0 +1,384 @@
+package sagns
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+func record(name, feature, id, lat, lon string) []string {
+	r := make([]string, 20)
+	r[0], r[1], r[2], r[3], r[4], r[5] = name, feature, id, lat, lon, "01-02-2003"
+	return r
+}
+
+func TestNewPOI(t *testing.T) {
+	old := record("", "Bow Lake", "103", "-33.6", "19.3")
+	old[9] = "Oude Meer"
+	tests := []struct {
+		name    string
+		record  []string
+		feature Feature
+		names   []poi.Name
+		tags    map[string]string
+	}{
+		{
+			"title case feature",
+			record("Tafelberg", "Mountain", "101", "-33.9625", "18.4039"),
+			"mountain",
+			[]poi.Name{{Key: poi.NameKeyDefault, Value: "Tafelberg"}},
+			map[string]string{"natural": "peak", "name": "Tafelberg", "sagns_id": "101", "source": "sagns"},
+		},
+		{
+			"multi word feature",
+			old,
+			"bow_lake",
+			[]poi.Name{{Key: poi.NameKeyOld, Value: "Oude Meer"}},
+			map[string]string{"natural": "water", "water": "oxbow", "old_name": "Oude Meer", "sagns_id": "103", "source": "sagns",
+				"fixme": "imported as a point, map as area"},
+		},
+		{
+			"normalised feature",
+			record("Bokrivier", " non-perennial ", "102", "-33.5", "19.2"),
+			"non_perennial",
+			[]poi.Name{{Key: poi.NameKeyDefault, Value: "Bokrivier"}},
+			map[string]string{"waterway": "river", "intermittent": "yes", "name": "Bokrivier", "sagns_id": "102", "source": "sagns",
+				"fixme": "imported as a point, map as line"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPOI(tt.record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Feature() != tt.feature {
+				t.Errorf("Feature() = %s, want %s", p.Feature(), tt.feature)
+			}
+			if !reflect.DeepEqual(p.Names(), tt.names) {
+				t.Errorf("Names() = %v, want %v", p.Names(), tt.names)
+			}
+			if !reflect.DeepEqual(p.Tags(), tt.tags) {
+				t.Errorf("Tags() = %v, want %v", p.Tags(), tt.tags)
+			}
+			if want := time.Date(2003, 2, 1, 0, 0, 0, 0, time.UTC); !p.date.Equal(want) {
+				t.Errorf("date = %s, want %s", p.date, want)
+			}
+		})
+	}
+}
+
+func TestNewPOIBadDate(t *testing.T) {
+	r := record("Tafelberg", "Mountain", "1", "-33.9", "18.4")
+	r[5] = "31-31-2003"
+	p, err := NewPOI(r)
+	if _, ok := err.(*DateError); !ok {
+		t.Fatalf("NewPOI() error = %v, want a DateError", err)
+	}
+	if p == nil || !p.date.IsZero() {
+		t.Fatalf("NewPOI() = %v, want the record kept without a date", p)
+	}
+}
+
+func TestReaderBadDate(t *testing.T) {
+	in := strings.Repeat("h,", 19) + "h\n" +
+		"Tafelberg,Mountain,1,-33.9,18.4,31-31-2003" + strings.Repeat(",", 14) + "\n"
+	r, err := NewReader(strings.NewReader(in), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := r.NextPOI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID() != 1 {
+		t.Errorf("read %v, want pklid 1", p)
+	}
+	errs := r.Errors()
+	if len(errs) != 1 || errs[0].Line != 2 || !errs[0].Warning {
+		t.Errorf("errors = %v, want a warning for line 2", errs)
+	}
+}
+
+func TestNewPOIErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   []string
+		unmapped bool
+	}{
+		{"bad latitude", record("Tafelberg", "Mountain", "1", "south", "18.4"), false},
+		{"bad longitude", record("Tafelberg", "Mountain", "1", "-33.9", "east"), false},
+		{"bad id", record("Tafelberg", "Mountain", "one", "-33.9", "18.4"), false},
+		{"short record", record("Tafelberg", "Mountain", "1", "-33.9", "18.4")[:19], false},
+		{"unknown feature", record("Tafelberg", "Volcano", "1", "-33.9", "18.4"), true},
+		{"feature without tags", record("Overberg", "Area", "1", "-33.9", "18.4"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPOI(tt.record)
+			if err == nil {
+				t.Fatalf("NewPOI() = %v, want error", p)
+			}
+			if _, ok := err.(*UnmappedError); ok != tt.unmapped {
+				t.Errorf("NewPOI() error = %v, unmapped %t", err, tt.unmapped)
+			}
+		})
+	}
+}
+
+func TestFeatureOSMTags(t *testing.T) {
+	tests := []struct {
+		feature Feature
+		want    map[string]string
+	}{
+		{"Agrivillage", map[string]string{"place": "village"}},
+		{"Bow Lake", map[string]string{"natural": "water", "water": "oxbow"}},
+		{"bow_lake", map[string]string{"natural": "water", "water": "oxbow"}},
+		{"Airfield", map[string]string{"aeroway": "aerodrome", "aerodrome:type": "airfield"}},
+		{"Area", map[string]string{}},
+		{"Volcano", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.feature), func(t *testing.T) {
+			if got := tt.feature.OSMTags(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OSMTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	tags := Feature("agrivillage").OSMTags()
+	tags["place"] = "town"
+	if got := Feature("agrivillage").OSMTags()["place"]; got != "village" {
+		t.Errorf("modifying OSMTags() changed the mapping to %s", got)
+	}
+}
+
+func TestNewFeature(t *testing.T) {
+	for _, d := range []string{"Bow Lake", "bow_lake", " BOW-LAKE ", "Bow  Lake"} {
+		if got := NewFeature(d); got != "bow_lake" {
+			t.Errorf("NewFeature(%q) = %q, want bow_lake", d, got)
+		}
+	}
+}
+
+func TestFeatureFilter(t *testing.T) {
+	got := Feature("Agrivillage").filter()
+	if len(got) != 5 || got[0].Key != "place" {
+		t.Errorf("filter() = %v, want all place values", got)
+	}
+	if got := Feature("Volcano").filter(); got != nil {
+		t.Errorf("filter() of unknown feature = %v", got)
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	peak, err := NewPOI(record("Tafelberg", "Mountain", "101", "-33.9625", "18.4039"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hill, err := NewPOI(record("Leeukop", "Hill", "102", "-33.93", "18.39"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []poi.Attribute{{Key: "sagns_id", Value: "101"}, {Key: "natural", Value: "peak"}}
+	if got := peak.OSMFilter(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OSMFilter() = %v, want %v", got, want)
+	}
+	want = []poi.Attribute{{Key: "sagns_id"}, {Key: "natural", Value: "peak"}}
+	if got := QueryFilter([]poi.POI{peak, hill}); !reflect.DeepEqual(got, want) {
+		t.Errorf("QueryFilter() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	for _, path := range []string{"testdata/features.json", "testdata/features.csv"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := LoadMapping(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Len() != 3 {
+				t.Errorf("Len() = %d, want 3", m.Len())
+			}
+			fm, ok := m.Lookup("mountain")
+			if !ok {
+				t.Fatal("mountain not mapped")
+			}
+			wantFilter := []poi.Attribute{{Key: "natural", Value: "peak"}, {Key: "natural", Value: "hill"}}
+			if !reflect.DeepEqual(fm.filter(), wantFilter) {
+				t.Errorf("filter() = %v, want %v", fm.filter(), wantFilter)
+			}
+			fm, ok = m.Lookup("bow_lake")
+			if !ok {
+				t.Fatal("bow_lake not mapped")
+			}
+			if fm.Geometry != GeometryArea || !reflect.DeepEqual(fm.Tags, map[string]string{"natural": "water", "water": "oxbow"}) {
+				t.Errorf("bow_lake mapped to %#v", fm)
+			}
+			if fm, _ := m.Lookup("volcano"); fm.Geometry != "" || len(fm.Filter) != 0 {
+				t.Errorf("volcano mapped to %#v", fm)
+			}
+		})
+	}
+	if _, err := LoadMapping("testdata/sagns.csv"); err == nil {
+		t.Error("LoadMapping() of SAGNS data should fail")
+	}
+	if _, err := LoadMapping("sagns_test.go"); err == nil {
+		t.Error("LoadMapping() of unsupported format should fail")
+	}
+}
+
+func TestNewMappingDuplicates(t *testing.T) {
+	_, err := NewMapping([]FeatureMapping{{Feature: "Non Perennial"}, {Feature: "Non_Perennial"}})
+	if err == nil {
+		t.Error("NewMapping() should reject features equal after normalising")
+	}
+}
+
+func TestReader(t *testing.T) {
+	m, err := LoadMapping("testdata/features.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		mapping  *Mapping
+		box      poi.Box
+		ids      []uint32
+		unmapped Unmapped
+	}{
+		{"default mapping", DefaultMapping, nil, []uint32{101, 103}, Unmapped{"river": 1, "area": 1, "volcano": 2}},
+		{"loaded mapping", m, nil, []uint32{101, 103, 105, 106}, Unmapped{"river": 1, "area": 1}},
+		{"in box", DefaultMapping, poi.CircleBox{Lat: -33.6, Lon: 19.3, RadiusKM: 5}, []uint32{103}, Unmapped{"river": 1, "area": 1, "volcano": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			UseMapping(tt.mapping)
+			defer UseMapping(DefaultMapping)
+			r, err := Open("testdata/sagns.csv", tt.box)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			pois, err := sources.ReadAll(r, poi.BBox{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]uint32, 0, len(pois))
+			for _, p := range pois {
+				ids = append(ids, p.(*SAGNSPOI).ID())
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("read %v, want %v", ids, tt.ids)
+			}
+			if !reflect.DeepEqual(r.Unmapped, tt.unmapped) {
+				t.Errorf("unmapped = %v, want %v", r.Unmapped, tt.unmapped)
+			}
+			errs := r.Errors()
+			if len(errs) != 2 || errs[0].Line != 8 || errs[1].Line != 9 {
+				t.Errorf("errors = %v, want lines 8 and 9", errs)
+			}
+		})
+	}
+}
+
+func TestReaderWindows1252(t *testing.T) {
+	pois, err := Read("testdata/sagns-1252.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pois) != 1 {
+		t.Fatalf("read %d POIs, want 1", len(pois))
+	}
+	if got, want := pois[0].Names()[0].Value, "Rivière se Kop’"; got != want {
+		t.Errorf("name = %q, want %q", got, want)
+	}
+}
+
+func TestNewReaderErrors(t *testing.T) {
+	for _, in := range []string{"", "Name,Feature\n"} {
+		if _, err := NewReader(strings.NewReader(in), nil); err == nil {
+			t.Errorf("NewReader(%q) should fail", in)
+		}
+	}
+}
+
+func TestUnmappedString(t *testing.T) {
+	u := Unmapped{"river": 1, "area": 1, "volcano": 2}
+	if got, want := u.String(), "volcano (2), area (1), river (1)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestIDFile(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/sagns.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	export := filepath.Join(t.TempDir(), "sagns.csv")
+	if err := ioutil.WriteFile(export, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pois, err := Read(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploaded := make([]sources.Uploaded, 0, 1)
+	for _, p := range pois {
+		if p.ID() == 101 {
+			uploaded = append(uploaded, sources.Uploaded{POI: p, OSMID: 9001})
+		}
+	}
+	if failed, err := (Source{}).RecordOSMIDs(export, uploaded); err != nil || len(failed) > 0 {
+		t.Fatalf("RecordOSMIDs() = %v, %v", failed, err)
+	}
+	saved, err := ioutil.ReadFile(IDFilePath(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sagns_id,osmid\n101,9001\n"; string(saved) != want {
+		t.Errorf("sidecar = %q, want %q", saved, want)
+	}
+	r, err := Open(export, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	rest, err := sources.ReadAll(r, poi.BBox{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 1 || rest[0].(*SAGNSPOI).ID() != 103 || r.Uploaded != 1 {
+		t.Errorf("read %v skipping %d, want only 103 skipping 1", rest, r.Uploaded)
+	}
+	ids, err := LoadIDFile(IDFilePath(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids.Add(103, 9002)
+	if err := ids.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = LoadIDFile(IDFilePath(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := ids.Lookup(103); !ok || id != 9002 || ids.Len() != 2 {
+		t.Errorf("Lookup(103) = %d, %t with %d IDs, want 9002 with 2", id, ok, ids.Len())
+	}
+}
+
+func TestLoadIDFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.csv")
+	if err := ioutil.WriteFile(path, []byte("sagns_id,osmid\n101,node\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadIDFile(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("LoadIDFile() error = %v, want invalid osmid on line 2", err)
+	}
+}
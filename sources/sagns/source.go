@@ -0,0 +1,54 @@
+package sagns
+
+import (
+	"fmt"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+func init() {
+	sources.Register(Source{})
+}
+
+// Source reads SAGNS CSV exports.
+type Source struct{}
+
+func (Source) Name() string {
+	return "sagns"
+}
+
+func (Source) Open(path string) (sources.Reader, error) {
+	return Open(path, nil)
+}
+
+func (Source) DedupeKey() string {
+	return "sagns_id"
+}
+
+func (Source) Filter() []poi.Attribute {
+	return []poi.Attribute{{Key: "sagns_id"}}
+}
+
+// QueryFilter also selects the OSM elements matching the features of pois, so that they can be conflated by name.
+func (Source) QueryFilter(pois []poi.POI) []poi.Attribute {
+	return QueryFilter(pois)
+}
+
+// RecordOSMIDs adds the nodes uploaded POIs became to the sidecar IDFile of the export at path.
+func (Source) RecordOSMIDs(path string, uploaded []sources.Uploaded) ([]error, error) {
+	ids, err := LoadIDFile(IDFilePath(path))
+	if err != nil {
+		return nil, err
+	}
+	var failed []error
+	for _, u := range uploaded {
+		p, ok := u.POI.(*SAGNSPOI)
+		if !ok {
+			failed = append(failed, fmt.Errorf("could not record node %d: %s is not a SAGNS POI", u.OSMID, u.POI))
+			continue
+		}
+		ids.Add(p.ID(), u.OSMID)
+	}
+	return failed, ids.Save()
+}
@@ -0,0 +1,131 @@
+package sources
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+// Source is a dataset which can be imported into OSM.
+type Source interface {
+	Name() string
+	// Open starts reading the dataset at path, the meaning of which depends on the source.
+	Open(path string) (Reader, error)
+	// DedupeKey is the OSM tag identifying elements already imported from the source, e.g. sagns_id.
+	DedupeKey() string
+	// Filter selects the OSM elements to dedupe against.
+	Filter() []poi.Attribute
+}
+
+// Reader iterates over the POIs of a source, returning io.EOF once all have been read.
+type Reader interface {
+	Next() (poi.POI, error)
+	Close() error
+}
+
+// ErrorReporter is implemented by Readers which skip malformed records instead of failing.
+type ErrorReporter interface {
+	Errors() ErrorReport
+}
+
+// QueryFilterer is implemented by Sources which search OSM for more than Filter selects, depending on the POIs read.
+type QueryFilterer interface {
+	QueryFilter(pois []poi.POI) []poi.Attribute
+}
+
+// Uploaded is a POI which has been uploaded to OSM as the node OSMID.
+type Uploaded struct {
+	POI   poi.POI
+	OSMID uint64
+}
+
+// IDRecorder is implemented by Sources which remember the nodes their POIs were uploaded as, so that later runs do
+// not propose them again.
+type IDRecorder interface {
+	// RecordOSMIDs stores the OSM IDs of POIs read from path, as many as it can. It returns why each of the others
+	// could not be stored, or an error if none could.
+	RecordOSMIDs(path string, uploaded []Uploaded) (failed []error, err error)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Source)
+)
+
+// Register makes a source available by name. It panics if a source with the same name is already registered.
+func Register(s Source) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := registry[s.Name()]; ok {
+		panic("sources: Register called twice for source " + s.Name())
+	}
+	registry[s.Name()] = s
+}
+
+func Lookup(name string) (Source, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown source %q, available sources are %v", name, namesLocked())
+	}
+	return s, nil
+}
+
+// Names returns the sorted names of all registered sources.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadAll reads all POIs contained in b from r.
+func ReadAll(r Reader, b poi.Box) ([]poi.POI, error) {
+	pois := make([]poi.POI, 0, 1024)
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return pois, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b.Contains(p) {
+			pois = append(pois, p)
+		}
+	}
+}
+
+type sliceReader struct {
+	pois []poi.POI
+}
+
+// NewSliceReader returns a Reader over POIs which have already been loaded.
+func NewSliceReader(pois []poi.POI) Reader {
+	return &sliceReader{pois: pois}
+}
+
+func (s *sliceReader) Next() (poi.POI, error) {
+	if len(s.pois) == 0 {
+		return nil, io.EOF
+	}
+	p := s.pois[0]
+	s.pois = s.pois[1:]
+	return p, nil
+}
+
+func (s *sliceReader) Close() error {
+	return nil
+}
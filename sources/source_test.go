@@ -0,0 +1,96 @@
+package sources
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+type testSource struct {
+	name string
+}
+
+func (s testSource) Name() string                     { return s.name }
+func (s testSource) Open(path string) (Reader, error) { return NewSliceReader(nil), nil }
+func (s testSource) DedupeKey() string                { return "ref" }
+func (s testSource) Filter() []poi.Attribute          { return nil }
+
+type testPOI struct {
+	lat, lon float64
+}
+
+func (p testPOI) String() string           { return "test" }
+func (p testPOI) Latitude() float64        { return p.lat }
+func (p testPOI) Longitude() float64       { return p.lon }
+func (p testPOI) Names() []poi.Name        { return nil }
+func (p testPOI) Tags() map[string]string  { return nil }
+func (p testPOI) AddTag(key, value string) {}
+
+func TestRegistry(t *testing.T) {
+	Register(testSource{"test-b"})
+	Register(testSource{"test-a"})
+	s, err := Lookup("test-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name() != "test-a" {
+		t.Errorf("Lookup() = %s, want test-a", s.Name())
+	}
+	if _, err := Lookup("missing"); err == nil {
+		t.Error("Lookup() of unknown source should fail")
+	}
+	if got := Names(); !reflect.DeepEqual(got, []string{"test-a", "test-b"}) {
+		t.Errorf("Names() = %v", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate source should panic")
+		}
+	}()
+	Register(testSource{"test-a"})
+}
+
+func TestReadAll(t *testing.T) {
+	in := testPOI{-33.5, 18.5}
+	out := testPOI{-30, 18.5}
+	r := NewSliceReader([]poi.POI{in, out, in})
+	got, err := ReadAll(r, poi.BBox{MinLat: -34, MaxLat: -33, MinLon: 18, MaxLon: 19})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []poi.POI{in, in}) {
+		t.Errorf("ReadAll() = %v", got)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next() after end = %v, want io.EOF", err)
+	}
+}
+
+// failingReader returns its POIs and then err.
+type failingReader struct {
+	pois []poi.POI
+	err  error
+}
+
+func (r *failingReader) Next() (poi.POI, error) {
+	if len(r.pois) == 0 {
+		return nil, r.err
+	}
+	p := r.pois[0]
+	r.pois = r.pois[1:]
+	return p, nil
+}
+
+func (r *failingReader) Close() error { return nil }
+
+func TestReadAllError(t *testing.T) {
+	want := errors.New("disk on fire")
+	r := &failingReader{pois: []poi.POI{testPOI{-33.5, 18.5}}, err: want}
+	got, err := ReadAll(r, poi.BBox{})
+	if err != want || got != nil {
+		t.Errorf("ReadAll() = %v, %v, want %v", got, err, want)
+	}
+}
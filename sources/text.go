@@ -0,0 +1,69 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252 to runes, the remaining bytes being equal to their Latin-1
+// code points.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+type textReader struct {
+	r       *bufio.Reader
+	started bool
+	pending []byte
+}
+
+// NewTextReader returns a reader of UTF-8 text from r, dropping a leading byte order mark and decoding any bytes
+// which are not valid UTF-8 as Windows-1252, the encoding some government exports are shipped in.
+func NewTextReader(r io.Reader) io.Reader {
+	return &textReader{r: bufio.NewReader(r)}
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	if !t.started {
+		t.started = true
+		if b, err := t.r.Peek(len(utf8BOM)); err == nil && bytes.Equal(b, utf8BOM) {
+			t.r.Discard(len(utf8BOM))
+		}
+	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	var buf [utf8.UTFMax]byte
+	for n < len(p) {
+		r, size, err := t.r.ReadRune()
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		if r == utf8.RuneError && size == 1 {
+			t.r.UnreadRune()
+			b, _ := t.r.ReadByte()
+			r = decodeWindows1252(b)
+		}
+		l := utf8.EncodeRune(buf[:], r)
+		c := copy(p[n:], buf[:l])
+		t.pending = append(t.pending, buf[c:l]...)
+		n += c
+	}
+	return n, nil
+}
+
+func decodeWindows1252(b byte) rune {
+	if b >= 0x80 && b < 0xA0 {
+		return windows1252[b-0x80]
+	}
+	return rune(b)
+}
@@ -0,0 +1,111 @@
+package trig
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DSNEnv is the environment variable from which DefaultConfig reads a connection string.
+const DSNEnv = "TRIG_DB"
+
+// Config holds the settings for connecting to the trig database. A DSN, either key=value pairs, a postgres:// URL or a
+// SQLite database (see SQLitePath), takes precedence over the other fields. Fields left empty fall back to the standard
+// PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE and PGSSLMODE environment variables, and failing those to a local
+// osmpoi database without SSL.
+type Config struct {
+	DSN      string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	DBName   string
+	SSLMode  string
+}
+
+// DefaultConfig returns a Config with the DSN from the TRIG_DB environment variable, if set.
+func DefaultConfig() Config {
+	return Config{DSN: os.Getenv(DSNEnv)}
+}
+
+// RegisterFlags adds flags for each setting to fs, defaulting to the current values of c.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.DSN, "db", c.DSN, "trig database connection string or SQLite file, overriding the other -db flags, defaults to $"+DSNEnv)
+	fs.StringVar(&c.Host, "db-host", c.Host, "trig database host, defaults to $PGHOST or localhost")
+	fs.IntVar(&c.Port, "db-port", c.Port, "trig database port, defaults to $PGPORT or 5432")
+	fs.StringVar(&c.User, "db-user", c.User, "trig database user, defaults to $PGUSER or osmpoi")
+	fs.StringVar(&c.Password, "db-password", c.Password, "trig database password, prefer setting $PGPASSWORD")
+	fs.StringVar(&c.DBName, "db-name", c.DBName, "trig database name, defaults to $PGDATABASE or osmpoi")
+	fs.StringVar(&c.SSLMode, "db-sslmode", c.SSLMode, "trig database SSL mode, defaults to $PGSSLMODE or disable")
+}
+
+// ConnString returns the connection string for c.
+func (c Config) ConnString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	params := make([]string, 0, 6)
+	add := func(key, val, env, def string) {
+		if val == "" && os.Getenv(env) == "" {
+			val = def
+		}
+		if val != "" {
+			params = append(params, key+"="+quoteParam(val))
+		}
+	}
+	port := ""
+	if c.Port != 0 {
+		port = strconv.Itoa(c.Port)
+	}
+	add("host", c.Host, "PGHOST", "")
+	add("port", port, "PGPORT", "")
+	add("user", c.User, "PGUSER", "osmpoi")
+	add("password", c.Password, "PGPASSWORD", "")
+	add("dbname", c.DBName, "PGDATABASE", "osmpoi")
+	add("sslmode", c.SSLMode, "PGSSLMODE", "disable")
+	return strings.Join(params, " ")
+}
+
+// SQLitePath returns the SQLite database named by the DSN, if it has a sqlite: or file: prefix or is the path of a
+// .db, .sqlite or .sqlite3 file.
+func (c Config) SQLitePath() (string, bool) {
+	switch {
+	case strings.HasPrefix(c.DSN, "sqlite:"):
+		path := strings.TrimPrefix(c.DSN, "sqlite:")
+		return strings.TrimPrefix(path, "//"), true
+	case strings.HasPrefix(c.DSN, "file:"):
+		return c.DSN, true
+	}
+	if strings.Contains(c.DSN, "://") || strings.Contains(c.DSN, "=") {
+		return "", false
+	}
+	switch strings.ToLower(filepath.Ext(c.DSN)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return c.DSN, true
+	}
+	return "", false
+}
+
+// String returns the connection string with any password hidden, for logging.
+func (c Config) String() string {
+	if path, ok := c.SQLitePath(); ok {
+		return "sqlite " + path
+	}
+	if c.DSN != "" {
+		return "dsn from -db or $" + DSNEnv
+	}
+	c.Password = ""
+	return c.ConnString()
+}
+
+// quoteParam quotes a key=value connection parameter if it contains spaces, quotes or backslashes.
+func quoteParam(val string) string {
+	if !strings.ContainsAny(val, ` '\`) {
+		return val
+	}
+	val = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(val)
+	return fmt.Sprintf("'%s'", val)
+}
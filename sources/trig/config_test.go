@@ -0,0 +1,103 @@
+package trig
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+func unsetPGEnv(t *testing.T) {
+	for _, env := range []string{"PGHOST", "PGPORT", "PGUSER", "PGPASSWORD", "PGDATABASE", "PGSSLMODE", DSNEnv} {
+		val, ok := os.LookupEnv(env)
+		os.Unsetenv(env)
+		if ok {
+			t.Cleanup(func() { os.Setenv(env, val) })
+		}
+	}
+}
+
+func TestConfigConnString(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		env  map[string]string
+		want string
+	}{
+		{"defaults", Config{}, nil, "user=osmpoi dbname=osmpoi sslmode=disable"},
+		{"dsn wins", Config{DSN: "postgres://u@db/trig", User: "other"}, nil, "postgres://u@db/trig"},
+		{
+			"fields",
+			Config{Host: "db.example.org", Port: 5433, User: "ngi", Password: "s3cret", DBName: "trig", SSLMode: "require"},
+			nil,
+			"host=db.example.org port=5433 user=ngi password=s3cret dbname=trig sslmode=require",
+		},
+		{"environment", Config{Host: "db"}, map[string]string{"PGUSER": "env", "PGDATABASE": "envdb", "PGSSLMODE": "verify-full"}, "host=db"},
+		{"quoted", Config{Password: `it's a \secret`}, nil, `user=osmpoi password='it\'s a \\secret' dbname=osmpoi sslmode=disable`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetPGEnv(t)
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+			}
+			if got := tt.cfg.ConnString(); got != tt.want {
+				t.Errorf("ConnString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigString(t *testing.T) {
+	unsetPGEnv(t)
+	cfg := Config{User: "ngi", Password: "s3cret"}
+	if got, want := cfg.String(), "user=ngi dbname=osmpoi sslmode=disable"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	cfg.DSN = "postgres://ngi:s3cret@db/trig"
+	if got := cfg.String(); got == cfg.DSN {
+		t.Errorf("String() leaks DSN %q", got)
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	unsetPGEnv(t)
+	os.Setenv(DSNEnv, "postgres://env/trig")
+	cfg := DefaultConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if cfg.ConnString() != "postgres://env/trig" {
+		t.Errorf("DefaultConfig() did not read $%s: %q", DSNEnv, cfg.ConnString())
+	}
+	err := fs.Parse([]string{"-db", "", "-db-host", "db", "-db-port", "5433", "-db-name", "trig"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.ConnString(), "host=db port=5433 user=osmpoi dbname=trig sslmode=disable"; got != want {
+		t.Errorf("ConnString() = %q, want %q", got, want)
+	}
+}
+
+func TestConfigSQLitePath(t *testing.T) {
+	tests := []struct {
+		dsn    string
+		want   string
+		sqlite bool
+	}{
+		{"sqlite:trig.db", "trig.db", true},
+		{"sqlite:///var/lib/trig", "/var/lib/trig", true},
+		{"file:trig?mode=memory", "file:trig?mode=memory", true},
+		{"/tmp/trig.sqlite", "/tmp/trig.sqlite", true},
+		{"trig.DB", "trig.DB", true},
+		{"postgres://db/trig.db", "", false},
+		{"dbname=trig.db", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			got, ok := Config{DSN: tt.dsn}.SQLitePath()
+			if got != tt.want || ok != tt.sqlite {
+				t.Errorf("SQLitePath() = %q, %v, want %q, %v", got, ok, tt.want, tt.sqlite)
+			}
+		})
+	}
+}
@@ -3,97 +3,87 @@ package trig
 import (
 	"database/sql"
 	"fmt"
+	"math"
 
+	"github.com/godfried/osmimport/poi"
 	"github.com/lib/pq"
 )
 
+// DB is a Store in a Postgres database with PostGIS.
 type DB struct {
-	conn *sql.DB
+	*sqlStore
 }
 
-func Connect() (*DB, error) {
-	connStr := "user=osmpoi dbname=osmpoi password=secret sslmode=disable"
-	conn, err := sql.Open("postgres", connStr)
+// Connect opens the trig database described by cfg and checks that it can be reached.
+func Connect(cfg Config) (*DB, error) {
+	conn, err := sql.Open("postgres", cfg.ConnString())
 	if err != nil {
 		return nil, err
 	}
-	return &DB{conn: conn}, nil
-}
-
-func (db *DB) Close() error {
-	return db.conn.Close()
-}
-
-func (db *DB) CreateTable() error {
-	q := `
-	CREATE TABLE IF NOT EXISTS trigbeacons (
-		name        			varchar NOT NULL,
-		lat         			real NOT NULL,
-		lon   					real NOT NULL,
-		ele         			real NOT NULL,
-		beacon_area_number      integer NOT NULL,
-		beacon_number          	integer NOT NULL,
-		description         	varchar,
-		created_by 				varchar,
-		osmid					bigint,
-		PRIMARY KEY(beacon_area_number, beacon_number)
-	);
-`
-	_, err := db.conn.Exec(q)
-	return err
+	err = conn.Ping()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not connect to trig database (%s): %s", cfg, err)
+	}
+	return &DB{newSQLStore(conn, migrations)}, nil
 }
 
-func (db *DB) QueryIncomplete(limit int) ([]*Trig, error) {
-	rows, err := db.conn.Query(fmt.Sprintf("SELECT * from trigbeacons WHERE osmid = 0 LIMIT %d;", limit))
+// QueryIncompleteWithin returns the beacons without an OSM ID inside b, nearest to its centre first, or all of them if
+// b is zero.
+func (db *DB) QueryIncompleteWithin(b poi.CircleBox) ([]*Trig, error) {
+	if b.IsZero() {
+		return db.QueryIncomplete(math.MaxInt32)
+	}
+	stmt, err := db.stmt(`
+	SELECT ` + trigColumns + ` FROM trigbeacons
+	WHERE osmid = 0 AND removed_at IS NULL AND ST_DWithin(geom, ST_MakePoint($1, $2)::geography, $3)
+	ORDER BY geom <-> ST_MakePoint($1, $2)::geography;
+`)
 	if err != nil {
 		return nil, err
 	}
-	trigs := make([]*Trig, 0, 4096)
-	for rows.Next() {
-		t := &Trig{Number: &BeaconNumber{}}
-		err = rows.Scan(&t.Name, &t.Lat, &t.Lon, &t.Ele, &t.Number.Area, &t.Number.Number, &t.Description, &t.CreatedBy, &t.OSMID)
-		if err != nil {
-			return nil, err
-		}
-		trigs = append(trigs, t)
+	rows, err := stmt.Query(b.Lon, b.Lat, b.Radius())
+	if err != nil {
+		return nil, err
 	}
-	return trigs, nil
+	return scanTrigs(rows)
 }
 
-func (db *DB) UpdateOSMIDs(trigs []*Trig) error {
-	for _, t := range trigs {
-		err := db.UpdateOSMID(t)
-		if err != nil {
-			return err
-		}
+// QueryNearest returns the k beacons nearest to lat, lon, nearest first.
+func (db *DB) QueryNearest(lat, lon float64, k int) ([]*Trig, error) {
+	stmt, err := db.stmt(`
+	SELECT ` + trigColumns + ` FROM trigbeacons
+	WHERE removed_at IS NULL
+	ORDER BY geom <-> ST_MakePoint($1, $2)::geography
+	LIMIT $3;
+`)
+	if err != nil {
+		return nil, err
 	}
-	return nil
-}
-
-func (db *DB) UpdateOSMID(t *Trig) error {
-	_, err := db.conn.Exec(fmt.Sprintf("UPDATE trigbeacons SET osmid = %d WHERE beacon_area_number = %d AND beacon_number = %d;", t.OSMID, t.Number.Area, t.Number.Number))
+	rows, err := stmt.Query(lon, lat, k)
 	if err != nil {
-		return fmt.Errorf("error inserting osmid %d for %s: %s", t.OSMID, t.Number, err)
+		return nil, err
 	}
-	return nil
+	return scanTrigs(rows)
 }
 
-func (db *DB) Import(trigs []*Trig) error {
+// Import adds trigs read from sourceFile to the database.
+func (db *DB) Import(sourceFile string, trigs []*Trig) error {
+	err := checkDuplicates(trigs)
+	if err != nil {
+		return err
+	}
 	txn, err := db.conn.Begin()
 	if err != nil {
 		return err
 	}
-	stmt, err := txn.Prepare(pq.CopyIn("trigbeacons", "name", "lat", "lon", "ele", "beacon_area_number", "beacon_number", "description", "created_by", "osmid"))
+	defer txn.Rollback()
+	stmt, err := txn.Prepare(pq.CopyIn("trigbeacons", "name", "lat", "lon", "ele", "beacon_area_number", "beacon_number", "description", "created_by", "osmid", "source_file"))
 	if err != nil {
 		return err
 	}
-	dups := make(map[string]struct{}, len(trigs))
 	for _, t := range trigs {
-		if _, ok := dups[t.Number.String()]; ok {
-			return fmt.Errorf("error inserting %#v: already exists", t)
-		}
-		dups[t.Number.String()] = struct{}{}
-		_, err = stmt.Exec(t.Name, t.Lat, t.Lon, t.Ele, t.Number.Area, t.Number.Number, t.Description, t.CreatedBy, t.OSMID)
+		_, err = stmt.Exec(t.Name, t.Lat, t.Lon, t.Ele, t.Number.Area, t.Number.Number, t.Description, t.CreatedBy, t.OSMID, sourceFile)
 		if err != nil {
 			return fmt.Errorf("error inserting %#v: %s", t, err)
 		}
@@ -0,0 +1,177 @@
+package trig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+func testTrigs() []*Trig {
+	return []*Trig{
+		{Name: "TAFELBERG", Lat: -33.962612345, Lon: 18.403912345, Ele: 1085.2, Number: &BeaconNumber{33, 18}},
+		{Name: "LEEUKOP", Lat: -33.935512345, Lon: 18.389212345, Ele: 669, Number: &BeaconNumber{33, 19}},
+		{Name: "KOEBERG", Lat: -33.676, Lon: 18.431, Ele: 12, Number: &BeaconNumber{33, 20}},
+		{Name: "SWARTBERG", Lat: -33.35, Lon: 22.05, Ele: 2100, Number: &BeaconNumber{33, 21}, OSMID: 42},
+	}
+}
+
+// migrate brings db up to date and imports testTrigs into it.
+func migrate(t *testing.T, db Store) {
+	if err := db.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Import("test.kml", testTrigs()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(trigs []*Trig) []string {
+	ns := make([]string, 0, len(trigs))
+	for _, t := range trigs {
+		ns = append(ns, t.Name)
+	}
+	return ns
+}
+
+func TestQueryIncompleteWithin(t *testing.T) {
+	testStores(t, func(t *testing.T, db Store) {
+		migrate(t, db)
+		tests := []struct {
+			name string
+			box  poi.CircleBox
+			want []string
+		}{
+			{"table mountain", poi.CircleBox{Lat: -33.96, Lon: 18.40, RadiusKM: 5}, []string{"TAFELBERG", "LEEUKOP"}},
+			{"cape peninsula", poi.CircleBox{Lat: -33.70, Lon: 18.43, RadiusKM: 40}, []string{"KOEBERG", "LEEUKOP", "TAFELBERG"}},
+			{"complete beacons excluded", poi.CircleBox{Lat: -33.35, Lon: 22.05, RadiusKM: 5}, []string{}},
+			{"zero box", poi.CircleBox{}, []string{"TAFELBERG", "LEEUKOP", "KOEBERG"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				trigs, err := db.QueryIncompleteWithin(tt.box)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := names(trigs)
+				if len(got) != len(tt.want) {
+					t.Fatalf("QueryIncompleteWithin() = %v, want %v", got, tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("QueryIncompleteWithin() = %v, want %v", got, tt.want)
+						break
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestQueryNearest(t *testing.T) {
+	testStores(t, func(t *testing.T, db Store) {
+		migrate(t, db)
+		trigs, err := db.QueryNearest(-33.94, 18.39, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(trigs); len(got) != 2 || got[0] != "LEEUKOP" || got[1] != "TAFELBERG" {
+			t.Errorf("QueryNearest() = %v, want [LEEUKOP TAFELBERG]", got)
+		}
+		want := testTrigs()[1]
+		if trigs[0].Lat != want.Lat || trigs[0].Lon != want.Lon {
+			t.Errorf("coordinates lost precision: got %v,%v, want %v,%v", trigs[0].Lat, trigs[0].Lon, want.Lat, want.Lon)
+		}
+	})
+}
+
+func TestUpdateOSMIDs(t *testing.T) {
+	testStores(t, func(t *testing.T, db Store) {
+		migrate(t, db)
+		trigs := testTrigs()[:2]
+		trigs[0].OSMID, trigs[1].OSMID = 100, 101
+		missing := &Trig{Name: "MISSING", Number: &BeaconNumber{99, 99}, OSMID: 102}
+		if err := db.UpdateOSMIDs(append(trigs, missing)); err == nil {
+			t.Fatal("UpdateOSMIDs() with a missing beacon should fail")
+		}
+		incomplete, err := db.QueryIncomplete(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(incomplete) != 3 {
+			t.Errorf("failed batch updated beacons, %d incomplete, want 3", len(incomplete))
+		}
+		if err := db.UpdateOSMIDs(trigs); err != nil {
+			t.Fatal(err)
+		}
+		incomplete, err = db.QueryIncomplete(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(incomplete); len(got) != 1 || got[0] != "KOEBERG" {
+			t.Errorf("QueryIncomplete() = %v, want [KOEBERG]", got)
+		}
+		if err := db.UpdateOSMID(missing); err == nil {
+			t.Error("UpdateOSMID() of a missing beacon should fail")
+		}
+	})
+}
+
+func TestRecordOSMIDs(t *testing.T) {
+	cfg := Config{DSN: filepath.Join(t.TempDir(), "trig.db")}
+	UseConfig(cfg)
+	defer UseConfig(DefaultConfig())
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrate(t, db)
+	trigs := testTrigs()
+	missing := &Trig{Name: "NOWHERE", Lat: -34, Lon: 19, Number: &BeaconNumber{34, 1}}
+	uploaded := []sources.Uploaded{{POI: trigs[0], OSMID: 1001}, {POI: missing, OSMID: 1003}, {POI: trigs[2], OSMID: 1002}}
+	failed, err := (Source{}).RecordOSMIDs("", uploaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 {
+		t.Errorf("RecordOSMIDs() failed = %v, want the missing beacon only", failed)
+	}
+	if trigs[0].OSMID != 1001 {
+		t.Errorf("OSMID = %d, want 1001", trigs[0].OSMID)
+	}
+	incomplete, err := db.QueryIncomplete(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(incomplete); len(got) != 1 || got[0] != "LEEUKOP" {
+		t.Errorf("QueryIncomplete() = %v, want [LEEUKOP]", got)
+	}
+}
+
+func TestSourceOpenBox(t *testing.T) {
+	cfg := Config{DSN: filepath.Join(t.TempDir(), "trig.db")}
+	UseConfig(cfg)
+	defer UseConfig(DefaultConfig())
+	UseBox(poi.CircleBox{Lat: -33.96, Lon: 18.40, RadiusKM: 5})
+	defer UseBox(poi.CircleBox{})
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrate(t, db)
+	r, err := (Source{}).Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	pois, err := sources.ReadAll(r, poi.BBox{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pois) != 2 {
+		t.Errorf("Open() read %v, want the 2 incomplete beacons in the box", pois)
+	}
+}
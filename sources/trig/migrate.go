@@ -0,0 +1,162 @@
+package trig
+
+import (
+	"fmt"
+	"log"
+)
+
+type migration struct {
+	version     int
+	description string
+	// extensions are the Postgres extensions the migration needs, enabled before its statements.
+	extensions []string
+	statements []string
+}
+
+// migrations bring the Postgres trig schema up to date, each applied once in order of version. Released migrations must
+// never be edited, add a new one instead.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create trigbeacons",
+		statements: []string{`
+	CREATE TABLE IF NOT EXISTS trigbeacons (
+		name        			varchar NOT NULL,
+		lat         			real NOT NULL,
+		lon   					real NOT NULL,
+		ele         			real NOT NULL,
+		beacon_area_number      integer NOT NULL,
+		beacon_number          	integer NOT NULL,
+		description         	varchar,
+		created_by 				varchar,
+		osmid					bigint,
+		PRIMARY KEY(beacon_area_number, beacon_number)
+	);
+`},
+	},
+	{
+		version:     2,
+		description: "index beacons by OSM ID",
+		statements: []string{
+			`CREATE INDEX IF NOT EXISTS trigbeacons_osmid_idx ON trigbeacons (osmid);`,
+		},
+	},
+	{
+		version:     3,
+		description: "record import times and source files",
+		statements: []string{
+			`ALTER TABLE trigbeacons ADD COLUMN IF NOT EXISTS imported_at timestamptz NOT NULL DEFAULT now();`,
+			`ALTER TABLE trigbeacons ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();`,
+			`ALTER TABLE trigbeacons ADD COLUMN IF NOT EXISTS source_file varchar;`,
+		},
+	},
+	{
+		// Coordinates stored as real have already lost precision, re-import the beacons to restore it.
+		version:     4,
+		description: "store coordinates in double precision with a PostGIS geography point",
+		extensions:  []string{"postgis"},
+		statements: []string{
+			`ALTER TABLE trigbeacons
+				ALTER COLUMN lat TYPE double precision,
+				ALTER COLUMN lon TYPE double precision,
+				ALTER COLUMN ele TYPE double precision;`,
+			`ALTER TABLE trigbeacons ADD COLUMN geom geography(Point, 4326)
+				GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(lon, lat), 4326)::geography) STORED;`,
+			`CREATE INDEX IF NOT EXISTS trigbeacons_geom_idx ON trigbeacons USING GIST (geom);`,
+		},
+	},
+	{
+		version:     5,
+		description: "track beacons removed and changed by re-imports",
+		statements: []string{
+			`ALTER TABLE trigbeacons ADD COLUMN IF NOT EXISTS removed_at timestamptz;`,
+			`CREATE TABLE IF NOT EXISTS trigbeacon_changes (
+				id                 serial PRIMARY KEY,
+				beacon_area_number integer NOT NULL,
+				beacon_number      integer NOT NULL,
+				kind               varchar NOT NULL,
+				field              varchar,
+				old_value          varchar,
+				new_value          varchar,
+				source_file        varchar,
+				changed_at         timestamptz NOT NULL DEFAULT now()
+			);`,
+			`CREATE INDEX IF NOT EXISTS trigbeacon_changes_beacon_idx ON trigbeacon_changes (beacon_area_number, beacon_number);`,
+		},
+	},
+}
+
+// SchemaVersion returns the version of the last migration applied, 0 if none have been.
+func (db *sqlStore) SchemaVersion() (int, error) {
+	err := db.createMigrationsTable()
+	if err != nil {
+		return 0, err
+	}
+	var version int
+	err = db.conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`).Scan(&version)
+	return version, err
+}
+
+// LatestSchemaVersion is the version Migrate brings the schema up to.
+func (db *sqlStore) LatestSchemaVersion() int {
+	return db.migrations[len(db.migrations)-1].version
+}
+
+// Migrate applies the migrations newer than the schema's version, each in its own transaction, returning how many
+// were applied.
+func (db *sqlStore) Migrate() (int, error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, m := range db.migrations {
+		if m.version <= version {
+			continue
+		}
+		err = db.apply(m)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %s", m.version, m.description, err)
+		}
+		log.Printf("applied migration %d: %s", m.version, m.description)
+		applied++
+	}
+	return applied, nil
+}
+
+func (db *sqlStore) createMigrationsTable() error {
+	_, err := db.conn.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     integer PRIMARY KEY,
+		description varchar NOT NULL,
+		applied_at  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+`)
+	return err
+}
+
+func (db *sqlStore) apply(m migration) error {
+	txn, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+	for _, ext := range m.extensions {
+		_, err = txn.Exec(`CREATE EXTENSION IF NOT EXISTS ` + ext + `;`)
+		if err != nil {
+			return fmt.Errorf("could not enable the %s extension (%s). Creating it needs a superuser, "+
+				"so have one run CREATE EXTENSION %s; in the trig database and migrate again", ext, err, ext)
+		}
+	}
+	for _, s := range m.statements {
+		_, err = txn.Exec(s)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = txn.Exec(`INSERT INTO schema_migrations (version, description) VALUES ($1, $2);`, m.version, m.description)
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
@@ -0,0 +1,126 @@
+package trig
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testStores runs test against an empty SQLite store and, if $TRIG_TEST_DB is set, the Postgres database it names.
+// The Postgres database's trig tables are dropped before and after the test.
+func testStores(t *testing.T, test func(t *testing.T, db Store)) {
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := Open(Config{DSN: filepath.Join(t.TempDir(), "trig.db")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		test(t, db)
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, testDB(t))
+	})
+}
+
+// testDB connects to the Postgres database in $TRIG_TEST_DB, skipping the test if it is not set. The database needs
+// PostGIS installed, or a role allowed to create it, e.g.
+//
+//	createdb trig_test && psql -d trig_test -c 'CREATE EXTENSION postgis;'
+//	TRIG_TEST_DB='dbname=trig_test sslmode=disable' go test ./sources/trig
+//
+// Its trig tables are dropped by the tests.
+func testDB(t *testing.T) *DB {
+	dsn := os.Getenv("TRIG_TEST_DB")
+	if dsn == "" {
+		t.Skip("TRIG_TEST_DB not set")
+	}
+	db, err := Connect(Config{DSN: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drop := func() {
+		_, err := db.conn.Exec(`DROP TABLE IF EXISTS trigbeacons, trigbeacon_changes, schema_migrations;`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	drop()
+	t.Cleanup(func() {
+		drop()
+		db.Close()
+	})
+	return db
+}
+
+// sqlConn returns the connection underlying db, for inspecting it directly.
+func sqlConn(db Store) *sql.DB {
+	switch db := db.(type) {
+	case *DB:
+		return db.conn
+	case *SQLiteDB:
+		return db.conn
+	}
+	panic(fmt.Sprintf("unknown store %T", db))
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for name, ms := range map[string][]migration{"postgres": migrations, "sqlite": sqliteMigrations} {
+		for i, m := range ms {
+			if m.version != i+1 {
+				t.Errorf("%s migration %d has version %d, want %d", name, i, m.version, i+1)
+			}
+			if m.description == "" || len(m.statements) == 0 {
+				t.Errorf("%s migration %d is incomplete", name, m.version)
+			}
+		}
+		if latest := newSQLStore(nil, ms).LatestSchemaVersion(); latest != len(ms) {
+			t.Errorf("%s LatestSchemaVersion() = %d, want %d", name, latest, len(ms))
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	testStores(t, func(t *testing.T, db Store) {
+		applied, err := db.Migrate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied != db.LatestSchemaVersion() {
+			t.Errorf("Migrate() applied %d, want %d", applied, db.LatestSchemaVersion())
+		}
+		applied, err = db.Migrate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied != 0 {
+			t.Errorf("second Migrate() applied %d, want 0", applied)
+		}
+		version, err := db.SchemaVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != db.LatestSchemaVersion() {
+			t.Errorf("SchemaVersion() = %d, want %d", version, db.LatestSchemaVersion())
+		}
+	})
+}
+
+func TestMigrateMissingExtension(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "trig.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// SQLite has no extensions, standing in for a Postgres role which may not create them.
+	db.migrations = []migration{{version: 1, description: "needs postgis", extensions: []string{"postgis"}, statements: []string{"SELECT 1;"}}}
+	_, err = db.Migrate()
+	if err == nil || !strings.Contains(err.Error(), "run CREATE EXTENSION postgis;") {
+		t.Errorf("Migrate() error = %v, want instructions to create postgis", err)
+	}
+	if version, err := db.SchemaVersion(); err != nil || version != 0 {
+		t.Errorf("SchemaVersion() = %d, %v, want 0", version, err)
+	}
+}
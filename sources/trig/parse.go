@@ -2,13 +2,17 @@ package trig
 
 import (
 	"encoding/gob"
-	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+	"github.com/godfried/osmimport/sources/kml"
 )
 
 func ReadGOB(gobfile string) ([]*Trig, error) {
@@ -37,52 +41,103 @@ outer:
 	return trigs, nil
 }
 
+// ReadFile reads the trig beacons in a KML or KMZ file, logging the placemarks skipped because they are not beacons.
 func ReadFile(kmlFile string) ([]*Trig, error) {
-	kml, err := ParseFile(kmlFile)
+	r, err := OpenKML(kmlFile)
 	if err != nil {
 		return nil, err
 	}
-	return kml.ExtractPoints(), nil
+	defer r.Close()
+	return readAll(kmlFile, r)
 }
 
-func Read(r io.Reader) ([]*Trig, error) {
-	kml, err := Parse(r)
-	if err != nil {
-		return nil, err
-	}
-	return kml.ExtractPoints(), nil
+// Read reads the trig beacons in a KML document, logging the placemarks skipped because they are not beacons.
+func Read(in io.Reader) ([]*Trig, error) {
+	return readAll("KML", NewReader(in))
 }
 
-func Parse(r io.Reader) (*KML, error) {
-	kml := new(KML)
-	d := xml.NewDecoder(r)
-	err := d.Decode(kml)
-	if err != nil {
-		return nil, err
+func readAll(name string, r *Reader) ([]*Trig, error) {
+	trigs := make([]*Trig, 0, 4096)
+	for {
+		t, err := r.NextTrig()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		trigs = append(trigs, t)
 	}
-	return kml, nil
+	if len(r.Errors()) > 0 {
+		log.Printf("skipped %d placemarks in %s:\n%s", len(r.Errors()), name, r.Errors())
+	}
+	return trigs, nil
+}
+
+// Reader streams trig beacons from the placemarks of KML documents, skipping and collecting the placemarks which
+// cannot be read as beacons.
+type Reader struct {
+	r      *kml.Reader
+	file   string
+	errors sources.ErrorReport
 }
 
-func ParseFile(kmlFile string) (*KML, error) {
-	f, err := os.Open(kmlFile)
+// OpenKML starts reading the KML or KMZ file at path.
+func OpenKML(path string) (*Reader, error) {
+	r, err := kml.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	return Parse(f)
+	return &Reader{r: r}, nil
+}
+
+// NewReader reads a KML document from in.
+func NewReader(in io.Reader) *Reader {
+	return &Reader{r: kml.NewReader(in)}
 }
 
-func (k KML) ExtractPoints() []*Trig {
-	trigs := make([]*Trig, 0, len(k.Document.Folder.Placemark))
-	for _, p := range k.Document.Folder.Placemark {
+// NextTrig returns the next beacon, or io.EOF once all have been read.
+func (r *Reader) NextTrig() (*Trig, error) {
+	for {
+		p, err := r.r.Next()
+		if err != nil {
+			return nil, err
+		}
 		t, err := newTrig(p)
 		if err != nil {
-			log.Print(err)
+			r.errors = append(r.errors, &sources.RecordError{
+				Line:   p.Line,
+				Record: []string{p.Name},
+				Err:    fmt.Errorf("placemark %q: %w", p.Name, err),
+			})
 			continue
 		}
-		trigs = append(trigs, t)
+		r.file = p.File
+		return t, nil
+	}
+}
+
+// Next implements sources.Reader.
+func (r *Reader) Next() (poi.POI, error) {
+	t, err := r.NextTrig()
+	if err != nil {
+		return nil, err
 	}
-	return trigs
+	return t, nil
+}
+
+// File returns the KML document the last beacon was read from, the name of the file inside the archive for a KMZ.
+func (r *Reader) File() string {
+	return r.file
+}
+
+// Errors returns the placemarks skipped so far.
+func (r *Reader) Errors() sources.ErrorReport {
+	return r.errors
+}
+
+func (r *Reader) Close() error {
+	return r.r.Close()
 }
 
 func newBeaconNumber(val string) (*BeaconNumber, error) {
@@ -101,16 +156,18 @@ func newBeaconNumber(val string) (*BeaconNumber, error) {
 	return &BeaconNumber{Area: area, Number: number}, nil
 }
 
-func newTrig(p Placemark) (*Trig, error) {
+// newTrig reads a beacon from the fields of p, preferring the coordinates of its point to those in its fields.
+func newTrig(p *kml.Placemark) (*Trig, error) {
 	t := &Trig{tags: make(map[string]string, 8)}
+	fields := p.Fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	var err error
-	descs := strings.Split(strings.TrimSuffix(strings.TrimPrefix(p.Description, "<![CDATA["), "]]>"), "<br></br>")
-	for _, d := range descs {
-		vals := strings.Split(d, "=")
-		if len(vals) < 2 {
-			continue
-		}
-		k, v := strings.ToLower(strings.TrimSpace(vals[0])), strings.TrimSpace(vals[1])
+	for _, key := range keys {
+		k, v := strings.ToLower(strings.ReplaceAll(key, "_", " ")), fields[key]
 		if v == "" {
 			continue
 		}
@@ -120,19 +177,18 @@ func newTrig(p Placemark) (*Trig, error) {
 		case "latitude":
 			t.Lat, err = strconv.ParseFloat(v, 64)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse latitude in: %#v: %s", p, err)
+				return nil, fmt.Errorf("cannot parse latitude: %s", err)
 			}
 		case "longitude":
 			t.Lon, err = strconv.ParseFloat(v, 64)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse longitude in: %#v: %s", p, err)
+				return nil, fmt.Errorf("cannot parse longitude: %s", err)
 			}
 		case "ortho ht":
 			t.Ele, err = strconv.ParseFloat(v, 64)
 			if err != nil {
-				return nil, fmt.Errorf("cannot parse elevation in: %#v: %s", p, err)
+				return nil, fmt.Errorf("cannot parse elevation: %s", err)
 			}
-		case "lo", "y", "x":
 		case "beacon number":
 			t.Number, err = newBeaconNumber(v)
 			if err != nil {
@@ -143,34 +199,28 @@ func newTrig(p Placemark) (*Trig, error) {
 		case "created by":
 			t.CreatedBy = v
 		default:
-			panic("unhandled key: " + k)
+			// Other fields, such as lo, x and y or the OBJECTID and styling attributes of GIS exports, are ignored.
 		}
 	}
 	if t.Number == nil {
-		return nil, fmt.Errorf("beacon number not set in: %#v", p)
-	}
-	lat, lon, err := parseCoords(p.Point.Coordinates)
-	if err != nil {
-		log.Print(err)
-	} else {
-		t.Lat = lat
-		t.Lon = lon
+		return nil, fmt.Errorf("beacon number not set")
 	}
-	return t, nil
-}
-
-func parseCoords(val string) (lat, lon float64, err error) {
-	coords := strings.Split(val, ",")
-	if len(coords) < 2 {
-		return 0, 0, fmt.Errorf("cannot parse coordinates: %s", val)
+	if t.Name == "" {
+		t.Name = p.Name
 	}
-	lon, err = strconv.ParseFloat(coords[0], 64)
-	if err != nil {
-		return 0, 0, err
+	if p.Point != nil {
+		lat, lon, err := p.Point.LatLon()
+		if err == nil {
+			t.Lat, t.Lon = lat, lon
+			return t, nil
+		}
+		if t.Lat == 0 && t.Lon == 0 {
+			return nil, err
+		}
+		log.Printf("%s %s: %s, using the coordinates in its description", t.Number, t.Name, err)
 	}
-	lat, err = strconv.ParseFloat(coords[1], 64)
-	if err != nil {
-		return 0, 0, err
+	if t.Lat == 0 && t.Lon == 0 {
+		return nil, fmt.Errorf("no coordinates")
 	}
-	return lat, lon, err
+	return t, nil
 }
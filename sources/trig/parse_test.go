@@ -0,0 +1,150 @@
+package trig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+	"github.com/godfried/osmimport/sources/kml"
+)
+
+func TestNewBeaconNumber(t *testing.T) {
+	tests := []struct {
+		val     string
+		want    *BeaconNumber
+		wantErr bool
+	}{
+		{"33-18", &BeaconNumber{Area: 33, Number: 18}, false},
+		{"123-4", &BeaconNumber{Area: 123, Number: 4}, false},
+		{"3318", nil, true},
+		{"33-18-1", nil, true},
+		{"a-18", nil, true},
+		{"33-b", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			got, err := newBeaconNumber(tt.val)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newBeaconNumber(%q) error = %v, wantErr %t", tt.val, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newBeaconNumber(%q) = %v, want %v", tt.val, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTrig(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       kml.Placemark
+		want    *Trig
+		wantErr bool
+	}{
+		{
+			"coordinates from point",
+			kml.Placemark{
+				Description: "Name=TAFELBERG<br></br>Beacon Number=33-18<br></br>Latitude=-33.96<br></br>Longitude=18.40<br></br>Ortho Ht=1085.2<br></br>LO=19<br></br>Description=Pillar<br></br>Created By=NGI",
+				Point:       &kml.Point{Coordinates: "18.4039,-33.9626,0"},
+			},
+			&Trig{Name: "TAFELBERG", Lat: -33.9626, Lon: 18.4039, Ele: 1085.2, Number: &BeaconNumber{33, 18}, Description: "Pillar", CreatedBy: "NGI"},
+			false,
+		},
+		{
+			"coordinates from description",
+			kml.Placemark{
+				Description: "<![CDATA[Name=LEEUKOP<br></br>Beacon Number=33-19<br></br>Latitude=-33.9355<br></br>Longitude=18.3892<br></br>Ortho Ht=]]>",
+			},
+			&Trig{Name: "LEEUKOP", Lat: -33.9355, Lon: 18.3892, Number: &BeaconNumber{33, 19}},
+			false,
+		},
+		{
+			"extended data",
+			kml.Placemark{
+				Name: "KOEBERG",
+				ExtendedData: &kml.ExtendedData{SchemaData: []kml.SchemaData{{SimpleData: []kml.SimpleData{
+					{Name: "BEACON_NUMBER", Value: "33-20"},
+					{Name: "ORTHO_HT", Value: "12"},
+				}}}},
+				Point: &kml.Point{Coordinates: "18.431,-33.676"},
+			},
+			&Trig{Name: "KOEBERG", Lat: -33.676, Lon: 18.431, Ele: 12, Number: &BeaconNumber{33, 20}},
+			false,
+		},
+		{"missing beacon number", kml.Placemark{Description: "Name=X<br></br>Latitude=-33.9"}, nil, true},
+		{"bad latitude", kml.Placemark{Description: "Name=X<br></br>Latitude=south"}, nil, true},
+		{"bad beacon number", kml.Placemark{Description: "Beacon Number=3318"}, nil, true},
+		{
+			"unknown field",
+			kml.Placemark{
+				Description:  "Beacon Number=33-18<br></br>Latitude=-33.9<br></br>Longitude=18.4<br></br>Colour=red",
+				ExtendedData: &kml.ExtendedData{Data: []kml.Data{{Name: "OBJECTID", Value: "7"}}},
+			},
+			&Trig{Lat: -33.9, Lon: 18.4, Number: &BeaconNumber{33, 18}},
+			false,
+		},
+		{"no coordinates", kml.Placemark{Description: "Beacon Number=33-18", Point: &kml.Point{Coordinates: "bad"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTrig(&tt.p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTrig() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				return
+			}
+			got.tags = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newTrig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	trigs, err := ReadFile("testdata/beacons.kml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trigs) != 2 {
+		t.Fatalf("read %d trigs, want 2", len(trigs))
+	}
+	if trigs[0].Name != "TAFELBERG" || trigs[0].Lat != -33.9626 || trigs[0].Number.String() != "33-18" {
+		t.Errorf("unexpected trig %#v", trigs[0])
+	}
+	tags := trigs[0].Tags()
+	want := map[string]string{
+		"man_made":    "survey_point",
+		"ref":         "33-18",
+		"source":      "ngi",
+		"name":        "TAFELBERG",
+		"ele":         "1085.2",
+		"description": "Pillar",
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("Tags() = %v, want %v", tags, want)
+	}
+	if trigs[1].Lat != -33.9355 || trigs[1].Lon != 18.3892 {
+		t.Errorf("trig with bad point should keep description coordinates, got %f,%f", trigs[1].Lat, trigs[1].Lon)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	r, err := OpenKML("testdata/beacons.kml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := sources.ReadAll(r, poi.BBox{}); err != nil {
+		t.Fatal(err)
+	}
+	errs := r.Errors()
+	if len(errs) != 1 || errs[0].Record[0] != "NO NUMBER" {
+		t.Fatalf("Errors() = %v, want the placemark without a beacon number", errs)
+	}
+	if got, want := errs[0].Error(), `line 22: placemark "NO NUMBER": beacon number not set`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
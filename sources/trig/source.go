@@ -0,0 +1,116 @@
+package trig
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godfried/osmimport/poi"
+	"github.com/godfried/osmimport/sources"
+)
+
+func init() {
+	sources.Register(Source{})
+}
+
+var (
+	configMu sync.RWMutex
+	config   *Config
+	box      poi.CircleBox
+)
+
+// UseConfig sets the database Source reads beacons from and records their OSM IDs in, by default DefaultConfig.
+func UseConfig(cfg Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config = &cfg
+}
+
+// UseBox limits the beacons Source reads from the database to those within b, all are read if b is zero.
+func UseBox(b poi.CircleBox) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	box = b
+}
+
+func currentBox() poi.CircleBox {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return box
+}
+
+func currentConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if config == nil {
+		return DefaultConfig()
+	}
+	return *config
+}
+
+// Source reads trig beacons from a KML or KMZ file, or the beacons without an OSM ID within the box set by UseBox
+// from the database set by UseConfig if no path is given.
+type Source struct{}
+
+func (Source) Name() string {
+	return "trig"
+}
+
+func (Source) Open(path string) (sources.Reader, error) {
+	if path != "" {
+		return OpenKML(path)
+	}
+	db, err := Open(currentConfig())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	trigs, err := db.QueryIncompleteWithin(currentBox())
+	if err != nil {
+		return nil, err
+	}
+	pois := make([]poi.POI, 0, len(trigs))
+	for _, t := range trigs {
+		pois = append(pois, t)
+	}
+	return sources.NewSliceReader(pois), nil
+}
+
+func (Source) DedupeKey() string {
+	return "ref"
+}
+
+func (Source) Filter() []poi.Attribute {
+	return []poi.Attribute{{Key: "man_made", Value: "survey_point"}}
+}
+
+// RecordOSMIDs stores the IDs of the nodes uploaded beacons became in the database set by UseConfig, where they must
+// already have been imported, whichever path they were read from. Beacons missing from the database do not stop the
+// others being updated.
+func (Source) RecordOSMIDs(path string, uploaded []sources.Uploaded) ([]error, error) {
+	trigs := make([]*Trig, 0, len(uploaded))
+	var failed []error
+	for _, u := range uploaded {
+		t, ok := u.POI.(*Trig)
+		if !ok {
+			failed = append(failed, fmt.Errorf("could not record node %d: %s is not a trig beacon", u.OSMID, u.POI))
+			continue
+		}
+		t.OSMID = u.OSMID
+		trigs = append(trigs, t)
+	}
+	db, err := Open(currentConfig())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err = db.UpdateOSMIDs(trigs); err == nil {
+		return failed, nil
+	}
+	// The batch is rolled back as a whole, so retry one beacon at a time to keep the IDs which can be stored.
+	for _, t := range trigs {
+		if err := db.UpdateOSMID(t); err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed, nil
+}
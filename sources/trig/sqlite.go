@@ -0,0 +1,159 @@
+package trig
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/godfried/osmimport/poi"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations bring the SQLite trig schema up to date, see migrations. SQLite has no PostGIS, so there is no
+// geography column and spatial queries are answered in Go.
+var sqliteMigrations = []migration{
+	{
+		version:     1,
+		description: "create trigbeacons and trigbeacon_changes",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS trigbeacons (
+				name               text NOT NULL,
+				lat                real NOT NULL,
+				lon                real NOT NULL,
+				ele                real NOT NULL,
+				beacon_area_number integer NOT NULL,
+				beacon_number      integer NOT NULL,
+				description        text,
+				created_by         text,
+				osmid              integer,
+				imported_at        timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at         timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				source_file        text,
+				removed_at         timestamp,
+				PRIMARY KEY(beacon_area_number, beacon_number)
+			);`,
+			`CREATE INDEX IF NOT EXISTS trigbeacons_osmid_idx ON trigbeacons (osmid);`,
+			`CREATE INDEX IF NOT EXISTS trigbeacons_lat_lon_idx ON trigbeacons (lat, lon);`,
+			`CREATE TABLE IF NOT EXISTS trigbeacon_changes (
+				id                 integer PRIMARY KEY AUTOINCREMENT,
+				beacon_area_number integer NOT NULL,
+				beacon_number      integer NOT NULL,
+				kind               text NOT NULL,
+				field              text,
+				old_value          text,
+				new_value          text,
+				source_file        text,
+				changed_at         timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);`,
+			`CREATE INDEX IF NOT EXISTS trigbeacon_changes_beacon_idx ON trigbeacon_changes (beacon_area_number, beacon_number);`,
+		},
+	},
+}
+
+// SQLiteDB is a Store in an embedded SQLite database, for working without a Postgres server.
+type SQLiteDB struct {
+	*sqlStore
+}
+
+// OpenSQLite opens the SQLite database at path, creating it if it does not exist. The path may also be a file: URI or
+// :memory:.
+func OpenSQLite(path string) (*SQLiteDB, error) {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, sharing one connection serialises the updates instead of failing them as busy.
+	conn.SetMaxOpenConns(1)
+	err = conn.Ping()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not open trig database %s: %s", path, err)
+	}
+	return &SQLiteDB{newSQLStore(conn, sqliteMigrations)}, nil
+}
+
+// QueryIncompleteWithin returns the beacons without an OSM ID inside b, nearest to its centre first, or all of them if
+// b is zero.
+func (db *SQLiteDB) QueryIncompleteWithin(b poi.CircleBox) ([]*Trig, error) {
+	if b.IsZero() {
+		return db.QueryIncomplete(math.MaxInt32)
+	}
+	stmt, err := db.stmt(`
+	SELECT ` + trigColumns + ` FROM trigbeacons
+	WHERE osmid = 0 AND removed_at IS NULL AND lat BETWEEN $1 AND $2 AND lon BETWEEN $3 AND $4;
+`)
+	if err != nil {
+		return nil, err
+	}
+	bounds := b.Bounds()
+	rows, err := stmt.Query(bounds.MinLat, bounds.MaxLat, bounds.MinLon, bounds.MaxLon)
+	if err != nil {
+		return nil, err
+	}
+	trigs, err := scanTrigs(rows)
+	if err != nil {
+		return nil, err
+	}
+	return toTrigs(indexTrigs(trigs).Within(b.Lat, b.Lon, b.Radius())), nil
+}
+
+// QueryNearest returns the k beacons nearest to lat, lon, nearest first.
+func (db *SQLiteDB) QueryNearest(lat, lon float64, k int) ([]*Trig, error) {
+	stmt, err := db.stmt("SELECT " + trigColumns + " FROM trigbeacons WHERE removed_at IS NULL;")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	trigs, err := scanTrigs(rows)
+	if err != nil {
+		return nil, err
+	}
+	return toTrigs(indexTrigs(trigs).Nearest(lat, lon, k)), nil
+}
+
+func indexTrigs(trigs []*Trig) *poi.Index {
+	pois := make([]poi.POI, 0, len(trigs))
+	for _, t := range trigs {
+		pois = append(pois, t)
+	}
+	return poi.IndexPOIs(pois)
+}
+
+func toTrigs(pois []poi.POI) []*Trig {
+	trigs := make([]*Trig, 0, len(pois))
+	for _, p := range pois {
+		trigs = append(trigs, p.(*Trig))
+	}
+	return trigs
+}
+
+// Import adds trigs read from sourceFile to the database.
+func (db *SQLiteDB) Import(sourceFile string, trigs []*Trig) error {
+	err := checkDuplicates(trigs)
+	if err != nil {
+		return err
+	}
+	txn, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+	stmt, err := txn.Prepare(`
+	INSERT INTO trigbeacons (name, lat, lon, ele, beacon_area_number, beacon_number, description, created_by, osmid, source_file)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, t := range trigs {
+		_, err = stmt.Exec(t.Name, t.Lat, t.Lon, t.Ele, t.Number.Area, t.Number.Number, t.Description, t.CreatedBy, t.OSMID, sourceFile)
+		if err != nil {
+			return fmt.Errorf("error inserting %#v: %s", t, err)
+		}
+	}
+	return txn.Commit()
+}
@@ -0,0 +1,164 @@
+package trig
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/godfried/osmimport/poi"
+)
+
+// Store holds the trig beacons extracted from the KML files and the OSM IDs they have been matched to.
+type Store interface {
+	// CreateTable creates the trig tables or migrates them to the latest schema.
+	CreateTable() error
+	SchemaVersion() (int, error)
+	LatestSchemaVersion() int
+	Migrate() (int, error)
+	Import(sourceFile string, trigs []*Trig) error
+	Upsert(sourceFile string, trigs []*Trig) (*ImportSummary, error)
+	QueryIncomplete(limit int) ([]*Trig, error)
+	QueryIncompleteWithin(b poi.CircleBox) ([]*Trig, error)
+	QueryNearest(lat, lon float64, k int) ([]*Trig, error)
+	UpdateOSMID(t *Trig) error
+	UpdateOSMIDs(trigs []*Trig) error
+	Close() error
+}
+
+// Open opens the store described by cfg, an embedded SQLite database if its DSN names one and Postgres otherwise.
+func Open(cfg Config) (Store, error) {
+	if path, ok := cfg.SQLitePath(); ok {
+		return OpenSQLite(path)
+	}
+	return Connect(cfg)
+}
+
+// sqlStore implements the parts of Store which Postgres and SQLite share.
+type sqlStore struct {
+	conn       *sql.DB
+	migrations []migration
+	mu         sync.Mutex
+	stmts      map[string]*sql.Stmt
+}
+
+func newSQLStore(conn *sql.DB, migrations []migration) *sqlStore {
+	return &sqlStore{conn: conn, migrations: migrations, stmts: make(map[string]*sql.Stmt)}
+}
+
+func (db *sqlStore) Close() error {
+	db.mu.Lock()
+	for _, stmt := range db.stmts {
+		stmt.Close()
+	}
+	db.stmts = nil
+	db.mu.Unlock()
+	return db.conn.Close()
+}
+
+// stmt returns query prepared once per connection pool, which is safe for concurrent use.
+func (db *sqlStore) stmt(query string) (*sql.Stmt, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if stmt, ok := db.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	db.stmts[query] = stmt
+	return stmt, nil
+}
+
+// CreateTable creates the trigbeacons table or migrates it to the latest schema.
+func (db *sqlStore) CreateTable() error {
+	_, err := db.Migrate()
+	return err
+}
+
+// trigColumns are the columns scanned into a Trig by scanTrigs.
+const trigColumns = "name, lat, lon, ele, beacon_area_number, beacon_number, description, created_by, osmid"
+
+func (db *sqlStore) QueryIncomplete(limit int) ([]*Trig, error) {
+	stmt, err := db.stmt("SELECT " + trigColumns + " FROM trigbeacons WHERE osmid = 0 AND removed_at IS NULL LIMIT $1;")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.Query(limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanTrigs(rows)
+}
+
+func scanTrigs(rows *sql.Rows) ([]*Trig, error) {
+	defer rows.Close()
+	trigs := make([]*Trig, 0, 4096)
+	for rows.Next() {
+		t := &Trig{Number: &BeaconNumber{}}
+		err := rows.Scan(&t.Name, &t.Lat, &t.Lon, &t.Ele, &t.Number.Area, &t.Number.Number, &t.Description, &t.CreatedBy, &t.OSMID)
+		if err != nil {
+			return nil, err
+		}
+		trigs = append(trigs, t)
+	}
+	return trigs, rows.Err()
+}
+
+const updateOSMIDQuery = `
+	UPDATE trigbeacons SET osmid = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE beacon_area_number = $2 AND beacon_number = $3;
+`
+
+// UpdateOSMIDs sets the OSM IDs of trigs in a single transaction, updating none of them if any fails.
+func (db *sqlStore) UpdateOSMIDs(trigs []*Trig) error {
+	stmt, err := db.stmt(updateOSMIDQuery)
+	if err != nil {
+		return err
+	}
+	txn, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+	txStmt := txn.Stmt(stmt)
+	for _, t := range trigs {
+		err = updateOSMID(txStmt, t)
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+func (db *sqlStore) UpdateOSMID(t *Trig) error {
+	stmt, err := db.stmt(updateOSMIDQuery)
+	if err != nil {
+		return err
+	}
+	return updateOSMID(stmt, t)
+}
+
+func updateOSMID(stmt *sql.Stmt, t *Trig) error {
+	res, err := stmt.Exec(t.OSMID, t.Number.Area, t.Number.Number)
+	if err != nil {
+		return fmt.Errorf("error inserting osmid %d for %s: %s", t.OSMID, t.Number, err)
+	}
+	n, err := res.RowsAffected()
+	if err == nil && n == 0 {
+		return fmt.Errorf("error inserting osmid %d for %s: no such beacon", t.OSMID, t.Number)
+	}
+	return nil
+}
+
+// checkDuplicates fails if trigs contains a beacon number more than once.
+func checkDuplicates(trigs []*Trig) error {
+	dups := make(map[BeaconNumber]struct{}, len(trigs))
+	for _, t := range trigs {
+		if _, ok := dups[*t.Number]; ok {
+			return fmt.Errorf("error inserting %#v: already exists", t)
+		}
+		dups[*t.Number] = struct{}{}
+	}
+	return nil
+}
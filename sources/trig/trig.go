@@ -66,10 +66,28 @@ func (t Trig) Tags() map[string]string {
 	return tags
 }
 
+// SourceID implements poi.Identified with the beacon number.
+func (t Trig) SourceID() (string, bool) {
+	if t.Number == nil {
+		return "", false
+	}
+	return t.Number.String(), true
+}
+
 func (t Trig) OSMFilter() []poi.Attribute {
 	return []poi.Attribute{{Key: "ref", Value: t.Number.String()}}
 }
 
+// AltRefs returns the beacon number of p, if it is a Trig, without its area. Some survey points in OSM are tagged with
+// it as their ref, so it serves as a poi.Conflator AltKeys.
+func AltRefs(p poi.POI) []string {
+	t, ok := p.(*Trig)
+	if !ok || t.Number == nil {
+		return nil
+	}
+	return []string{strconv.Itoa(t.Number.Number)}
+}
+
 func (t Trig) String() string {
 	return fmt.Sprintf("%v", t.Tags())
 }
@@ -0,0 +1,223 @@
+package trig
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldChange is a beacon attribute which differs between imports.
+type FieldChange struct {
+	Field    string
+	Old, New string
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s %s -> %s", c.Field, c.Old, c.New)
+}
+
+// BeaconChange lists the attributes of a beacon changed by a re-import.
+type BeaconChange struct {
+	Number BeaconNumber
+	Name   string
+	Fields []FieldChange
+}
+
+func (c BeaconChange) String() string {
+	fields := make([]string, 0, len(c.Fields))
+	for _, f := range c.Fields {
+		fields = append(fields, f.String())
+	}
+	return fmt.Sprintf("%s %s: %s", c.Number, c.Name, strings.Join(fields, ", "))
+}
+
+// ImportSummary describes how an upsert changed the beacons relative to the previous import.
+type ImportSummary struct {
+	New       []*Trig
+	Changed   []BeaconChange
+	Removed   []BeaconNumber
+	Unchanged int
+}
+
+// Add merges o into s.
+func (s *ImportSummary) Add(o *ImportSummary) {
+	s.New = append(s.New, o.New...)
+	s.Changed = append(s.Changed, o.Changed...)
+	s.Removed = append(s.Removed, o.Removed...)
+	s.Unchanged += o.Unchanged
+}
+
+func (s *ImportSummary) String() string {
+	return fmt.Sprintf("%d new, %d changed, %d removed, %d unchanged", len(s.New), len(s.Changed), len(s.Removed), s.Unchanged)
+}
+
+// diffTrig lists the attributes of t which differ from old, ignoring the OSM ID.
+func diffTrig(old, t *Trig) []FieldChange {
+	changes := make([]FieldChange, 0, 6)
+	add := func(field, o, n string) {
+		if o != n {
+			changes = append(changes, FieldChange{Field: field, Old: o, New: n})
+		}
+	}
+	float := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	add("name", old.Name, t.Name)
+	add("lat", float(old.Lat), float(t.Lat))
+	add("lon", float(old.Lon), float(t.Lon))
+	add("ele", float(old.Ele), float(t.Ele))
+	add("description", old.Description, t.Description)
+	add("created_by", old.CreatedBy, t.CreatedBy)
+	return changes
+}
+
+type existingTrig struct {
+	*Trig
+	sourceFile string
+	removed    bool
+}
+
+// Upsert imports trigs read from sourceFile, inserting new beacons and updating existing ones while preserving their
+// OSM IDs. Beacons previously imported from sourceFile but missing from trigs are marked removed. Every change is
+// recorded in trigbeacon_changes.
+func (db *sqlStore) Upsert(sourceFile string, trigs []*Trig) (*ImportSummary, error) {
+	txn, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+	existing, err := queryExisting(txn, sourceFile, trigs)
+	if err != nil {
+		return nil, err
+	}
+	upsert, err := txn.Prepare(`
+	INSERT INTO trigbeacons (name, lat, lon, ele, beacon_area_number, beacon_number, description, created_by, osmid, source_file)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 0, $9)
+	ON CONFLICT (beacon_area_number, beacon_number) DO UPDATE SET
+		name = EXCLUDED.name, lat = EXCLUDED.lat, lon = EXCLUDED.lon, ele = EXCLUDED.ele,
+		description = EXCLUDED.description, created_by = EXCLUDED.created_by, source_file = EXCLUDED.source_file,
+		updated_at = CURRENT_TIMESTAMP, removed_at = NULL;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer upsert.Close()
+	record, err := txn.Prepare(`
+	INSERT INTO trigbeacon_changes (beacon_area_number, beacon_number, kind, field, old_value, new_value, source_file)
+	VALUES ($1, $2, $3, $4, $5, $6, $7);
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer record.Close()
+	summary := &ImportSummary{}
+	seen := make(map[BeaconNumber]struct{}, len(trigs))
+	for _, t := range trigs {
+		if _, ok := seen[*t.Number]; ok {
+			return nil, fmt.Errorf("error upserting %#v: duplicate beacon in %s", t, sourceFile)
+		}
+		seen[*t.Number] = struct{}{}
+		old, ok := existing[*t.Number]
+		var changes []FieldChange
+		switch {
+		case !ok:
+			summary.New = append(summary.New, t)
+			_, err = record.Exec(t.Number.Area, t.Number.Number, "new", nil, nil, nil, sourceFile)
+		default:
+			changes = diffTrig(old.Trig, t)
+			if old.sourceFile != sourceFile {
+				changes = append(changes, FieldChange{Field: "source_file", Old: old.sourceFile, New: sourceFile})
+			}
+			if old.removed {
+				changes = append(changes, FieldChange{Field: "removed", Old: "yes", New: "no"})
+			}
+			if len(changes) == 0 {
+				summary.Unchanged++
+				continue
+			}
+			summary.Changed = append(summary.Changed, BeaconChange{Number: *t.Number, Name: t.Name, Fields: changes})
+			for _, c := range changes {
+				_, err = record.Exec(t.Number.Area, t.Number.Number, "changed", c.Field, c.Old, c.New, sourceFile)
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error recording changes to %s: %s", t.Number, err)
+		}
+		t.OSMID = old.osmID()
+		_, err = upsert.Exec(t.Name, t.Lat, t.Lon, t.Ele, t.Number.Area, t.Number.Number, t.Description, t.CreatedBy, sourceFile)
+		if err != nil {
+			return nil, fmt.Errorf("error upserting %#v: %s", t, err)
+		}
+	}
+	remove, err := txn.Prepare(`UPDATE trigbeacons SET removed_at = CURRENT_TIMESTAMP WHERE beacon_area_number = $1 AND beacon_number = $2;`)
+	if err != nil {
+		return nil, err
+	}
+	defer remove.Close()
+	for n, old := range existing {
+		if _, ok := seen[n]; ok || old.removed || old.sourceFile != sourceFile {
+			continue
+		}
+		summary.Removed = append(summary.Removed, n)
+		_, err = remove.Exec(n.Area, n.Number)
+		if err == nil {
+			_, err = record.Exec(n.Area, n.Number, "removed", nil, nil, nil, sourceFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error removing %s: %s", n, err)
+		}
+	}
+	sort.Slice(summary.Removed, func(i, j int) bool {
+		a, b := summary.Removed[i], summary.Removed[j]
+		return a.Area < b.Area || a.Area == b.Area && a.Number < b.Number
+	})
+	return summary, txn.Commit()
+}
+
+func (e existingTrig) osmID() uint64 {
+	if e.Trig == nil {
+		return 0
+	}
+	return e.OSMID
+}
+
+// queryExisting loads the beacons previously imported from sourceFile or sharing an area with trigs.
+func queryExisting(txn *sql.Tx, sourceFile string, trigs []*Trig) (map[BeaconNumber]existingTrig, error) {
+	areaSet := make(map[int]struct{}, 4)
+	args := []interface{}{sourceFile}
+	params := make([]string, 0, 4)
+	for _, t := range trigs {
+		if _, ok := areaSet[t.Number.Area]; !ok {
+			areaSet[t.Number.Area] = struct{}{}
+			args = append(args, t.Number.Area)
+			params = append(params, "$"+strconv.Itoa(len(args)))
+		}
+	}
+	where := "source_file = $1"
+	if len(params) > 0 {
+		where += " OR beacon_area_number IN (" + strings.Join(params, ", ") + ")"
+	}
+	rows, err := txn.Query(`
+	SELECT `+trigColumns+`, COALESCE(source_file, ''), removed_at IS NOT NULL FROM trigbeacons
+	WHERE `+where+`;
+`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	existing := make(map[BeaconNumber]existingTrig, len(trigs))
+	for rows.Next() {
+		e := existingTrig{Trig: &Trig{Number: &BeaconNumber{}}}
+		err = rows.Scan(&e.Name, &e.Lat, &e.Lon, &e.Ele, &e.Number.Area, &e.Number.Number, &e.Description, &e.CreatedBy, &e.OSMID, &e.sourceFile, &e.removed)
+		if err != nil {
+			return nil, err
+		}
+		existing[*e.Number] = e
+	}
+	return existing, rows.Err()
+}
@@ -0,0 +1,101 @@
+package trig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTrig(t *testing.T) {
+	old := &Trig{Name: "TAFELBERG", Lat: -33.9626, Lon: 18.4039, Ele: 1085.2, Number: &BeaconNumber{33, 18}, OSMID: 42}
+	tests := []struct {
+		name string
+		t    Trig
+		want []FieldChange
+	}{
+		{"unchanged", *old, []FieldChange{}},
+		{"osm id ignored", Trig{Name: "TAFELBERG", Lat: -33.9626, Lon: 18.4039, Ele: 1085.2}, []FieldChange{}},
+		{
+			"moved and renamed",
+			Trig{Name: "TABLE MOUNTAIN", Lat: -33.96261, Lon: 18.4039, Ele: 1086, Description: "Pillar"},
+			[]FieldChange{
+				{Field: "name", Old: "TAFELBERG", New: "TABLE MOUNTAIN"},
+				{Field: "lat", Old: "-33.9626", New: "-33.96261"},
+				{Field: "ele", Old: "1085.2", New: "1086"},
+				{Field: "description", Old: "", New: "Pillar"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffTrig(old, &tt.t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffTrig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportSummary(t *testing.T) {
+	s := &ImportSummary{Unchanged: 2, Removed: []BeaconNumber{{33, 1}}}
+	s.Add(&ImportSummary{
+		New:       []*Trig{{Name: "A", Number: &BeaconNumber{33, 2}}},
+		Changed:   []BeaconChange{{Number: BeaconNumber{33, 3}, Name: "B", Fields: []FieldChange{{"ele", "1", "2"}}}},
+		Unchanged: 1,
+	})
+	if got, want := s.String(), "1 new, 1 changed, 1 removed, 3 unchanged"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got, want := s.Changed[0].String(), "33-3 B: ele 1 -> 2"; got != want {
+		t.Errorf("BeaconChange.String() = %q, want %q", got, want)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	testStores(t, func(t *testing.T, db Store) {
+		migrate(t, db)
+		trigs := testTrigs()
+		trigs[0].OSMID = 100
+		if err := db.UpdateOSMID(trigs[0]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sqlConn(db).Exec(`UPDATE trigbeacons SET source_file = 'test.kml';`); err != nil {
+			t.Fatal(err)
+		}
+		reimport := testTrigs()[:2]
+		reimport[0].Ele = 1086
+		reimport = append(reimport, &Trig{Name: "NOORDHOEK", Lat: -34.1, Lon: 18.38, Number: &BeaconNumber{34, 1}})
+		summary, err := db.Upsert("test.kml", reimport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := summary.String(), "1 new, 1 changed, 2 removed, 1 unchanged"; got != want {
+			t.Errorf("Upsert() = %s, want %s", got, want)
+		}
+		if want := []BeaconNumber{{33, 20}, {33, 21}}; !reflect.DeepEqual(summary.Removed, want) {
+			t.Errorf("removed %v, want %v", summary.Removed, want)
+		}
+		if reimport[0].OSMID != 100 {
+			t.Errorf("OSMID = %d, want preserved 100", reimport[0].OSMID)
+		}
+		nearest, err := db.QueryNearest(-33.9626, 18.4039, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nearest[0].OSMID != 100 || nearest[0].Ele != 1086 {
+			t.Errorf("stored %#v, want OSMID 100 and ele 1086", nearest[0])
+		}
+		var changes int
+		if err := sqlConn(db).QueryRow(`SELECT COUNT(*) FROM trigbeacon_changes;`).Scan(&changes); err != nil {
+			t.Fatal(err)
+		}
+		if changes != 4 {
+			t.Errorf("recorded %d changes, want 4", changes)
+		}
+		summary, err = db.Upsert("test.kml", reimport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := summary.String(), "0 new, 0 changed, 0 removed, 3 unchanged"; got != want {
+			t.Errorf("second Upsert() = %s, want %s", got, want)
+		}
+	})
+}
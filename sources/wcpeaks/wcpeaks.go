@@ -1,47 +1,100 @@
 package wcpeaks
 
 import (
-	"encoding/csv"
+	"fmt"
 	"io"
+	"log"
 	"os"
 
 	"strconv"
+
+	"github.com/godfried/osmimport/sources"
 )
 
 func Read(inputFile string) ([]*Peak, error) {
-	f, err := os.Open(inputFile)
+	r, err := Open(inputFile)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	r := csv.NewReader(f)
-	r.FieldsPerRecord = 3
-	r.Comma = ';'
+	defer r.Close()
 	peaks := make([]*Peak, 0, 1000)
 	for {
-		record, err := r.Read()
+		p, err := r.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return nil, err
 		}
-		p := NewPeak(record)
 		peaks = append(peaks, p)
 	}
+	if len(r.Errors()) > 0 {
+		log.Printf("skipped %d malformed records in %s:\n%s", len(r.Errors()), inputFile, r.Errors())
+	}
 	return peaks, nil
 }
 
-func NewPeak(record []string) *Peak {
+// Reader streams peaks from a semicolon separated file of name, range and elevation, skipping malformed records.
+type Reader struct {
+	records *sources.Records
+	closer  io.Closer
+}
+
+func Open(inputFile string) (*Reader, error) {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	r := NewReader(f)
+	r.closer = f
+	return r, nil
+}
+
+func NewReader(in io.Reader) *Reader {
+	return &Reader{records: sources.NewRecords(in, ';', 3)}
+}
+
+// Next returns the next peak, or io.EOF once all have been read.
+func (r *Reader) Next() (*Peak, error) {
+	for {
+		record, line, err := r.records.Next()
+		if err != nil {
+			return nil, err
+		}
+		p, err := NewPeak(record)
+		if err != nil {
+			r.records.Skip(line, record, err)
+			continue
+		}
+		return p, nil
+	}
+}
+
+// Errors returns the malformed records read so far.
+func (r *Reader) Errors() sources.ErrorReport {
+	return r.records.Errors
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+func NewPeak(record []string) (*Peak, error) {
+	if len(record) != 3 {
+		return nil, fmt.Errorf("expected 3 fields, got %d", len(record))
+	}
 	ele, err := strconv.ParseFloat(record[2], 64)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("elevation: %w", err)
 	}
 	return &Peak{
 		Name:  record[0],
 		Range: record[1],
 		Ele:   ele,
-	}
+	}, nil
 }
 
 type Peak struct {
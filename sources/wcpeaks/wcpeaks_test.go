@@ -0,0 +1,78 @@
+package wcpeaks
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	peaks, err := Read("testdata/peaks.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Peak{
+		{Name: "Sneeuberg", Range: "Cederberg", Ele: 2027},
+		{Name: "Table Mountain", Range: "Table Mountain", Ele: 1085},
+		{Name: "Matroosberg", Range: "Hex River Mountains", Ele: 2249.5},
+		{Name: "Tâfel", Range: "Hottentots Holland", Ele: 1590},
+	}
+	if !reflect.DeepEqual(peaks, want) {
+		t.Errorf("Read() = %v, want %v", peaks, want)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	r, err := Open("testdata/peaks.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for {
+		if _, err := r.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	errs := r.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Errors() = %v, want 2 errors", errs)
+	}
+	if errs[0].Line != 4 || !errors.Is(errs[0], strconv.ErrSyntax) {
+		t.Errorf("first error = %v, want bad elevation on line 4", errs[0])
+	}
+	if errs[1].Line != 5 || !errors.Is(errs[1], csv.ErrFieldCount) {
+		t.Errorf("second error = %v, want wrong field count on line 5", errs[1])
+	}
+}
+
+func TestNewPeak(t *testing.T) {
+	tests := []struct {
+		record  []string
+		want    *Peak
+		wantErr bool
+	}{
+		{[]string{"Sneeuberg", "Cederberg", "2027"}, &Peak{Name: "Sneeuberg", Range: "Cederberg", Ele: 2027}, false},
+		{[]string{"Sneeuberg", "Cederberg", "high"}, nil, true},
+		{[]string{"Sneeuberg", "Cederberg"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := NewPeak(tt.record)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewPeak(%v) error = %v, wantErr %t", tt.record, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NewPeak(%v) = %v, want %v", tt.record, got, tt.want)
+		}
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	if _, err := Read("testdata/missing.csv"); err == nil {
+		t.Error("Read() of missing file should fail")
+	}
+}